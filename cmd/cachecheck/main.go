@@ -0,0 +1,95 @@
+package main
+
+import (
+	"api_shope/cmd/database"
+	"api_shope/config"
+	"api_shope/internal/repository"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+)
+
+// cachecheck membandingkan isi cache redis dengan mysql dan melaporkan key yang menyimpang.
+// exit code 1 jika ada drift yang tidak diperbaiki, 2 jika pemeriksaan gagal.
+func main() {
+	repair := flag.Bool("repair", false, "tulis ulang key yang menyimpang dari mysql, sisanya dibuang")
+	evict := flag.Bool("evict", false, "buang key yang menyimpang")
+	families := flag.String("family", "", "family yang diperiksa dipisah koma ("+strings.Join(repository.CheckFamilies(), ",")+"), kosong berarti semua")
+	asJSON := flag.Bool("json", false, "tampilkan hasil sebagai json")
+	flag.Parse()
+
+	if *repair && *evict {
+		log.Fatal("-repair dan -evict tidak bisa dipakai bersamaan")
+	}
+
+	mode := repository.CheckOnly
+	switch {
+	case *repair:
+		mode = repository.CheckRepair
+	case *evict:
+		mode = repository.CheckEvict
+	}
+
+	var wanted []string
+	if *families != "" {
+		wanted = strings.Split(*families, ",")
+		for _, name := range wanted {
+			if !slices.Contains(repository.CheckFamilies(), name) {
+				log.Fatalf("family %q tidak dikenal", name)
+			}
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, rdb, err := database.ConnectDB(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer database.Close(db, rdb)
+
+	results, err := repository.NewCacheChecker(db, rdb, mode).Check(wanted...)
+	if *asJSON {
+		if err := json.NewEncoder(os.Stdout).Encode(results); err != nil {
+			log.Println(err)
+		}
+	} else {
+		printResults(results)
+	}
+	if err != nil {
+		log.Println("pemeriksaan berhenti:", err)
+		os.Exit(2)
+	}
+
+	for _, result := range results {
+		for _, drift := range result.Drift {
+			if drift.Action == "" {
+				os.Exit(1)
+			}
+		}
+	}
+}
+
+func printResults(results []repository.CheckResult) {
+	total := 0
+	for _, result := range results {
+		fmt.Printf("%-10s %5d key diperiksa, %d menyimpang\n", result.Family, result.Scanned, len(result.Drift))
+		for _, drift := range result.Drift {
+			action := drift.Action
+			if action == "" {
+				action = "-"
+			}
+			fmt.Printf("  %-9s %s: %s\n", action, drift.Key, drift.Reason)
+		}
+		total += len(result.Drift)
+	}
+
+	fmt.Printf("total %d key menyimpang\n", total)
+}
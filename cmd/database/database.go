@@ -1,42 +1,28 @@
 package database
 
 import (
+	"api_shope/config"
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"os"
 
-	"github.com/joho/godotenv"
 	"github.com/redis/go-redis/v9"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
-func ConnectDB() (*gorm.DB, *redis.Client, error) {
-	_ = godotenv.Load()
-
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbName := os.Getenv("DB_NAME")
-
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		dbUser, dbPassword, dbHost, dbPort, dbName)
-
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+func ConnectDB(cfg *config.Config) (*gorm.DB, *redis.Client, error) {
+	db, err := gorm.Open(mysql.Open(cfg.Database.DSN()), &gorm.Config{})
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to connect to DB: %w", err)
 	}
 	log.Println("✅ Connected to database successfully")
 
-	redisAddr := os.Getenv("REDIS_ADDR")
-	redisPassword := os.Getenv("REDIS_PASSWORD")
-
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     redisAddr,
-		Password: redisPassword,
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
 		DB:       0,
 	})
 
@@ -47,3 +33,20 @@ func ConnectDB() (*gorm.DB, *redis.Client, error) {
 
 	return db, rdb, nil
 }
+
+// Close menutup koneksi mysql dan redis saat aplikasi berhenti
+func Close(db *gorm.DB, rdb *redis.Client) error {
+	var errs []error
+
+	if sqlDB, err := db.DB(); err != nil {
+		errs = append(errs, err)
+	} else if err := sqlDB.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to close DB: %w", err))
+	}
+
+	if err := rdb.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to close Redis: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"api_shope/config"
+	"api_shope/internal/repository"
+	"api_shope/internal/worker"
+	"api_shope/utils/mailer"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// NewWorker menyusun worker dari config, dipakai server api dan binary worker
+func NewWorker(cfg *config.Config, db *gorm.DB, rdb *redis.Client, renderer *mailer.Renderer) (*worker.Worker, error) {
+	m, err := mailer.New(mailer.Config{
+		Driver:   cfg.Mail.Driver,
+		From:     cfg.Mail.Sender,
+		Host:     cfg.Mail.SMTPHost,
+		Port:     cfg.Mail.SMTPPort,
+		Username: cfg.Mail.Sender,
+		Password: cfg.Mail.Password,
+		TLS:      cfg.Mail.SMTPTLS,
+		Dir:      cfg.Mail.Dir,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	w := worker.NewWorker(db, rdb, m, renderer, WarmupOptions(cfg))
+	w.MaxAttempts = cfg.Worker.MaxAttempts
+	w.Concurrency = cfg.Worker.Concurrency
+
+	return w, nil
+}
+
+// WarmupOptions adalah batas beban warmup cache, dipakai saat boot dan cmd/warmcache
+func WarmupOptions(cfg *config.Config) repository.WarmupOptions {
+	return repository.WarmupOptions{
+		BatchSize: cfg.Cache.WarmupBatchSize,
+		QPS:       cfg.Cache.WarmupQPS,
+		HotStores: cfg.Cache.WarmupHotStores,
+	}
+}
@@ -2,64 +2,137 @@ package main
 
 import (
 	"api_shope/cmd/database"
+	"api_shope/cmd/jobs"
 	"api_shope/cmd/routes"
+	"api_shope/config"
 	"api_shope/internal/handler"
 	"api_shope/internal/repository"
 	"api_shope/internal/usecase"
 	"api_shope/internal/worker"
+	"api_shope/utils/helper"
+	"api_shope/utils/mailer"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
-
-	"github.com/joho/godotenv"
 )
 
 func main() {
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file")
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	db, rdb, err := database.ConnectDB()
+	db, rdb, err := database.ConnectDB(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	//auth
-	authRepo := repository.NewAuthRepo(db, rdb)
-	authUsecase := usecase.NewAuthUsecase(authRepo)
+	jwt := helper.NewJWT(cfg.JWT.Secret, cfg.JWT.AccessTTL, cfg.JWT.RefreshTTL)
+	authRepo := repository.NewAuthRepo(db, rdb, cfg.JWT.AccessTTL, cfg.JWT.RefreshTTL)
+	authUsecase := usecase.NewAuthUsecase(authRepo, jwt, cfg.App.URL, cfg.App.ResetURL)
 	authHandler := handler.NewAuthHandler(authUsecase)
 
+	//cache L1 product, aktif jika LOCAL_CACHE_SIZE > 0
+	listenCtx, stopListen := context.WithCancel(context.Background())
+	var l1 *repository.LocalCache
+	if cfg.Cache.LocalSize > 0 {
+		l1 = repository.NewLocalCache(rdb, cfg.Cache.LocalSize, cfg.Cache.LocalTTL)
+		go l1.Listen(listenCtx)
+	}
+
 	//shop
-	shopRepo := repository.NewShopRepo(db, rdb)
-	shopUsecase := usecase.NewShopUsecase(shopRepo)
+	shopRepo := repository.NewShopRepo(db, rdb, l1)
+	shopUsecase := usecase.NewShopUsecase(shopRepo, cfg.App.RequireVerifiedEmail)
 	shopHandler := handler.NewShopHandler(shopUsecase)
 
-	r := routes.SetupRoutes(authHandler, shopHandler)
-
-	go func() {
-		port := os.Getenv("PORT")
-		if port == "" {
-			port = "8080"
+	//warmup cache sebelum menerima request, aktif jika WARM_CACHE_ON_BOOT=true
+	if cfg.Cache.WarmOnBoot {
+		opts := jobs.WarmupOptions(cfg)
+		opts.Progress = func(stage string, done, total int) {
+			if done == total {
+				log.Printf("warmup %s: %d/%d", stage, done, total)
+			}
 		}
+		if result, err := shopRepo.Warmup(opts); err != nil {
+			log.Println("warmup cache dilewati:", err)
+		} else {
+			log.Printf("✅ Cache warmup selesai dalam %s", result.Duration.Round(time.Millisecond))
+		}
+	}
+
+	renderer, err := mailer.NewRenderer()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	//admin
+	queueRepo := repository.NewQueueRepo(rdb)
+	adminUsecase := usecase.NewAdminUsecase(queueRepo, shopRepo, renderer)
+	adminHandler := handler.NewAdminHandler(adminUsecase)
 
+	r := routes.SetupRoutes(authHandler, shopHandler, adminHandler, authRepo, jwt, cfg.App.AdminToken)
+
+	port := strconv.Itoa(cfg.App.Port)
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
 		fmt.Println("Server running on http://localhost:" + port)
-		log.Fatal(http.ListenAndServe(":"+port, r))
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
 	}()
 
-	//worker queue redis
-	w := worker.NewWorker(db, rdb)
-	w.StartFlushWorker(10 * time.Second)
-	log.Println("Worker started...")
+	//worker queue redis, matikan dengan RUN_WORKER=false jika worker dijalankan lewat cmd/worker
+	var w *worker.Worker
+	if cfg.Worker.RunInServer {
+		w, err = jobs.NewWorker(cfg, db, rdb, renderer)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := w.Start(); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("Worker started...")
+	}
 
 	stopChan := make(chan os.Signal, 1)
 	signal.Notify(stopChan, os.Interrupt, syscall.SIGTERM)
 
-	<-stopChan
-	log.Println("Stopping worker...")
-	defer w.StopFlushWorker()
+	select {
+	case <-stopChan:
+	case err := <-serverErr:
+		log.Println("server berhenti:", err)
+	}
+
+	// berhenti menerima request baru, tunggu request dan job yang berjalan sampai batas waktu
+	log.Println("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.App.ShutdownTimeout)
+	defer cancel()
 
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Println("server belum selesai saat batas waktu habis:", err)
+	}
+	if w != nil {
+		if err := w.Shutdown(shutdownCtx); err != nil {
+			log.Println("worker belum selesai saat batas waktu habis:", err)
+		}
+	}
+	stopListen()
+	if err := database.Close(db, rdb); err != nil {
+		log.Println(err)
+	}
+	log.Println("Server stopped")
 }
@@ -2,20 +2,134 @@ package main
 
 import (
 	"api_shope/cmd/database"
-	"api_shope/model"
+	"api_shope/config"
+	"api_shope/internal/migrate"
+	"api_shope/migrations"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"strconv"
 )
 
+const usage = `pemakaian: migrations [-dir migrations] <perintah>
+
+perintah:
+  up [n]         jalankan migrasi yang belum dijalankan (n langkah, bawaan semua)
+  down [n]       rollback n migrasi terakhir (bawaan 1)
+  redo           rollback lalu jalankan ulang migrasi terakhir
+  status         tampilkan migrasi yang sudah dan belum dijalankan
+  create <nama>  buat pasangan file up/down baru di -dir
+`
+
 func main() {
+	dir := flag.String("dir", "migrations", "folder file migrasi untuk perintah create")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	args := flag.Args()
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	if command == "create" {
+		if len(args) < 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		up, down, err := migrate.Create(*dir, args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("dibuat:", up)
+		fmt.Println("dibuat:", down)
+		return
+	}
+
+	switch command {
+	case "up", "down", "redo", "status":
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	steps := 0
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			log.Fatalf("jumlah langkah %q tidak valid", args[1])
+		}
+		steps = n
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	db, _, err := database.ConnectDB()
+	db, rdb, err := database.ConnectDB(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer database.Close(db, rdb)
 
-	if err := db.AutoMigrate(&model.User{}, &model.Store{}, &model.Product{}, model.CartItem{}); err != nil {
+	m, err := migrate.New(db, migrations.FS)
+	if err != nil {
 		log.Fatal(err)
 	}
 
-	log.Println("✅ Migrasi selesai dan database siap")
+	switch command {
+	case "up":
+		done, err := m.Up(steps)
+		printDone("up", done)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(done) == 0 {
+			log.Println("✅ Database sudah versi terbaru")
+			return
+		}
+		log.Println("✅ Migrasi selesai dan database siap")
+
+	case "down":
+		done, err := m.Down(steps)
+		printDone("down", done)
+		if err != nil && !errors.Is(err, migrate.ErrNothingToRun) {
+			log.Fatal(err)
+		}
+		if len(done) == 0 {
+			log.Println("tidak ada migrasi untuk di-rollback")
+		}
+
+	case "redo":
+		migration, err := m.Redo()
+		if err != nil {
+			log.Fatal(err)
+		}
+		printDone("redo", []migrate.Migration{*migration})
+
+	case "status":
+		statuses, err := m.Status()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Missing {
+				state += " (file tidak ada)"
+			}
+			fmt.Printf("%04d  %-30s %s\n", s.Version, s.Name, state)
+		}
+	}
+}
+
+func printDone(command string, done []migrate.Migration) {
+	for _, m := range done {
+		fmt.Printf("%-4s  %04d_%s\n", command, m.Version, m.Name)
+	}
 }
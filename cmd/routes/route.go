@@ -2,22 +2,31 @@ package routes
 
 import (
 	"api_shope/internal/handler"
+	"api_shope/utils/helper"
 	"api_shope/utils/middleware"
 	"net/http"
 
 	"github.com/gorilla/mux"
 )
 
-func SetupRoutes(auth *handler.AuthHandler, shop *handler.ShopHandler) *mux.Router {
+func SetupRoutes(auth *handler.AuthHandler, shop *handler.ShopHandler, admin *handler.AdminHandler, checker middleware.TokenChecker, jwt *helper.JWT, adminToken string) *mux.Router {
 	r := mux.NewRouter()
+	authMiddleware := middleware.AuthMiddleware(checker, jwt)
 
 	//auth
 	r.HandleFunc("/login", auth.Login).Methods(http.MethodPost)
 	r.HandleFunc("/register", auth.Register).Methods(http.MethodPost)
+	r.HandleFunc("/refresh", auth.Refresh).Methods(http.MethodPost)
+	r.Handle("/logout", authMiddleware(http.HandlerFunc(auth.Logout))).Methods(http.MethodPost)
+	r.HandleFunc("/verify-email", auth.VerifyEmail).Methods(http.MethodGet)
+	r.Handle("/verify-email/resend", authMiddleware(http.HandlerFunc(auth.ResendVerification))).Methods(http.MethodPost)
+	r.HandleFunc("/password/forgot", auth.ForgotPassword).Methods(http.MethodPost)
+	r.HandleFunc("/password/reset", auth.ResetPassword).Methods(http.MethodPost)
+	r.Handle("/password/change", authMiddleware(http.HandlerFunc(auth.ChangePassword))).Methods(http.MethodPost)
 
 	//shop
 	useM := r.PathPrefix("/shop").Subrouter()
-	useM.Use(middleware.AuthMiddleware)
+	useM.Use(authMiddleware)
 
 	//s_store
 	useM.HandleFunc("/get-store", shop.GetAllStore).Methods(http.MethodGet)
@@ -39,10 +48,29 @@ func SetupRoutes(auth *handler.AuthHandler, shop *handler.ShopHandler) *mux.Rout
 	cartItemRouter := useM.PathPrefix("/cart-item").Subrouter()
 
 	cartItemRouter.HandleFunc("/get-my-cart-item", shop.GetMyCartItems).Methods(http.MethodGet)
+	cartItemRouter.HandleFunc("/total", shop.GetMyCartTotal).Methods(http.MethodGet)
 	cartItemRouter.HandleFunc("/create/{productId}", shop.CreateCartItem).Methods(http.MethodPost)
 	cartItemRouter.HandleFunc("/update-amount/{cartItemId}/{productId}", shop.UpdateAmountCartItem).Methods(http.MethodPut)
 	cartItemRouter.HandleFunc("/update-paid/{cartItemId}/{productId}", shop.UpdatePaidCartItem).Methods(http.MethodPut)
 	cartItemRouter.HandleFunc("/delete/{cartItemId}", shop.DeleteCartItem).Methods(http.MethodDelete)
 
+	//s_order
+	useM.HandleFunc("/orders", shop.GetMyOrders).Methods(http.MethodGet)
+	useM.HandleFunc("/orders/checkout", shop.Checkout).Methods(http.MethodPost)
+	useM.HandleFunc("/orders/{orderId}", shop.GetMyOrder).Methods(http.MethodGet)
+
+	//admin
+	adminRouter := r.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(middleware.AdminMiddleware(adminToken))
+
+	adminRouter.HandleFunc("/jobs/dead", admin.ListDeadJobs).Methods(http.MethodGet)
+	adminRouter.HandleFunc("/jobs/dead/{jobId}/replay", admin.ReplayDeadJob).Methods(http.MethodPost)
+	adminRouter.HandleFunc("/queues", admin.GetQueueStats).Methods(http.MethodGet)
+	adminRouter.HandleFunc("/jobs/{jobId}", admin.GetJob).Methods(http.MethodGet)
+	adminRouter.HandleFunc("/jobs/{jobId}/retry", admin.RetryJob).Methods(http.MethodPost)
+	adminRouter.HandleFunc("/jobs/{jobId}", admin.DiscardJob).Methods(http.MethodDelete)
+	adminRouter.HandleFunc("/cache/metrics", admin.GetCacheMetrics).Methods(http.MethodGet)
+	adminRouter.HandleFunc("/mail/preview/{template}", admin.PreviewMail).Methods(http.MethodGet)
+
 	return r
 }
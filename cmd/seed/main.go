@@ -0,0 +1,80 @@
+package main
+
+import (
+	"api_shope/cmd/database"
+	"api_shope/config"
+	"api_shope/internal/repository"
+	"api_shope/internal/seed"
+	"flag"
+	"log"
+	"time"
+)
+
+// seed mengisi database dengan data contoh yang deterministik untuk demo dan load test.
+// semua user memakai email userNNNN@seed.local dan password yang sama.
+func main() {
+	var opts seed.Options
+	flag.Uint64Var(&opts.Seed, "seed", 1, "seed generator, nilai sama menghasilkan data sama")
+	flag.IntVar(&opts.Users, "users", 50, "jumlah user")
+	flag.IntVar(&opts.Stores, "stores", 10, "jumlah store, masing-masing dimiliki satu user")
+	flag.IntVar(&opts.ProductsPerStore, "products", 20, "jumlah product per store")
+	flag.IntVar(&opts.CartItemsPerUser, "cart-items", 3, "jumlah cart item per user")
+	flag.StringVar(&opts.Password, "password", "password123", "password semua user seed")
+	baseTime := flag.String("base-time", "", "created_at dihitung mundur dari waktu ini (RFC3339, \"now\" = sekarang, kosong = 2026-01-01)")
+	reset := flag.Bool("reset", false, "kosongkan semua tabel, cache, queue dan token sebelum mengisi")
+	resetOnly := flag.Bool("reset-only", false, "kosongkan semua tabel, cache, queue dan token tanpa mengisi")
+	flag.Parse()
+
+	switch *baseTime {
+	case "":
+	case "now":
+		opts.BaseTime = time.Now()
+	default:
+		t, err := time.Parse(time.RFC3339, *baseTime)
+		if err != nil {
+			log.Fatal("base-time tidak valid: ", err)
+		}
+		opts.BaseTime = t
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, rdb, err := database.ConnectDB(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer database.Close(db, rdb)
+
+	if *reset || *resetOnly {
+		if err := seed.Reset(db); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("✅ Semua tabel dikosongkan")
+
+		cleared, err := seed.ResetRedis(rdb)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("%d key queue dan token dibuang", cleared)
+	}
+
+	if !*resetOnly {
+		result, err := seed.Run(db, opts)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("✅ Seed selesai: %d user, %d store, %d product, %d cart item",
+			result.Users, result.Stores, result.Products, result.CartItems)
+	}
+
+	// list product/store pasti berubah dan setelah reset id baru bisa sama dengan id
+	// lama yang masih di cache, jadi cache shop dibuang seluruhnya
+	cleared, err := repository.NewCacheChecker(db, rdb, repository.CheckEvict).Clear()
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("%d key cache dibuang", cleared)
+}
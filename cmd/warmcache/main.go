@@ -0,0 +1,56 @@
+package main
+
+import (
+	"api_shope/cmd/database"
+	"api_shope/cmd/jobs"
+	"api_shope/config"
+	"api_shope/internal/repository"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// warmcache mengisi cache redis dari mysql, misalnya setelah redis restart.
+// nilai bawaan flag diambil dari config (WARMUP_BATCH_SIZE, WARMUP_QPS dan WARMUP_HOT_STORES).
+func main() {
+	os.Exit(run())
+}
+
+// run dipisah dari main supaya defer Close tetap jalan sebelum os.Exit
+func run() int {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+	opts := jobs.WarmupOptions(cfg)
+
+	flag.IntVar(&opts.BatchSize, "batch", opts.BatchSize, "jumlah product per batch (0 = 500)")
+	flag.Float64Var(&opts.QPS, "qps", opts.QPS, "maksimum query mysql per detik (0 = tanpa batas)")
+	flag.IntVar(&opts.HotStores, "hot-stores", opts.HotStores, "jumlah store terlaris yang dimuat (0 = 20, negatif = lewati)")
+	flag.Parse()
+
+	db, rdb, err := database.ConnectDB(cfg)
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+	defer database.Close(db, rdb)
+
+	opts.Progress = func(stage string, done, total int) {
+		fmt.Printf("%-12s %d/%d\n", stage, done, total)
+	}
+
+	// L1 berukuran 0 hanya dipakai untuk menyiarkan invalidasi ke instance api
+	l1 := repository.NewLocalCache(rdb, 0, 0)
+	result, err := repository.NewShopRepo(db, rdb, l1).Warmup(opts)
+	if err != nil {
+		log.Println("warmup gagal:", err)
+		return 1
+	}
+
+	fmt.Printf("selesai dalam %s: %d product, %d store terlaris\n", result.Duration.Round(time.Millisecond), result.Products, result.HotStores)
+	return 0
+}
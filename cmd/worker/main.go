@@ -0,0 +1,55 @@
+package main
+
+import (
+	"api_shope/cmd/database"
+	"api_shope/cmd/jobs"
+	"api_shope/config"
+	"api_shope/utils/mailer"
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, rdb, err := database.ConnectDB(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	renderer, err := mailer.NewRenderer()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w, err := jobs.NewWorker(cfg, db, rdb, renderer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := w.Start(); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Worker %s started with %d processor...", w.Consumer, w.Concurrency)
+
+	stopChan := make(chan os.Signal, 1)
+	signal.Notify(stopChan, os.Interrupt, syscall.SIGTERM)
+	<-stopChan
+
+	log.Println("Stopping worker...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.App.ShutdownTimeout)
+	defer cancel()
+
+	if err := w.Shutdown(shutdownCtx); err != nil {
+		log.Println("worker belum selesai saat batas waktu habis:", err)
+	}
+	if err := database.Close(db, rdb); err != nil {
+		log.Println(err)
+	}
+	log.Println("Worker stopped")
+}
@@ -0,0 +1,271 @@
+// Package config memuat konfigurasi aplikasi dari default, file yaml opsional
+// (CONFIG_FILE), .env dan environment, dengan urutan prioritas tersebut dari
+// yang paling lemah. env yang kosong dianggap tidak diisi, key yaml yang tidak
+// dikenal ditolak supaya salah ketik tidak diam-diam diabaikan.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	App      AppConfig      `yaml:"app"`
+	Database DatabaseConfig `yaml:"database"`
+	Redis    RedisConfig    `yaml:"redis"`
+	JWT      JWTConfig      `yaml:"jwt"`
+	Mail     MailConfig     `yaml:"mail"`
+	Worker   WorkerConfig   `yaml:"worker"`
+	Cache    CacheConfig    `yaml:"cache"`
+}
+
+type AppConfig struct {
+	Port int    `env:"PORT" yaml:"port" default:"8080"`
+	URL  string `env:"APP_URL" yaml:"url" default:"http://localhost:8080"`
+	// halaman frontend yang menerima ?token= dari email lupa password
+	ResetURL             string        `env:"RESET_URL" yaml:"reset_url" default:"http://localhost:3000/reset-password"`
+	AdminToken           string        `env:"ADMIN_TOKEN" yaml:"admin_token"`
+	RequireVerifiedEmail bool          `env:"REQUIRE_VERIFIED_EMAIL" yaml:"require_verified_email" default:"false"`
+	ShutdownTimeout      time.Duration `env:"SHUTDOWN_TIMEOUT" yaml:"shutdown_timeout" default:"30s"`
+}
+
+type DatabaseConfig struct {
+	Host     string `env:"DB_HOST" yaml:"host" default:"127.0.0.1"`
+	Port     int    `env:"DB_PORT" yaml:"port" default:"3306"`
+	User     string `env:"DB_USER" yaml:"user" default:"root"`
+	Password string `env:"DB_PASSWORD" yaml:"password"`
+	Name     string `env:"DB_NAME" yaml:"name"`
+}
+
+func (d DatabaseConfig) DSN() string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+		d.User, d.Password, d.Host, d.Port, d.Name)
+}
+
+type RedisConfig struct {
+	Addr     string `env:"REDIS_ADDR" yaml:"addr" default:"localhost:6379"`
+	Password string `env:"REDIS_PASSWORD" yaml:"password"`
+}
+
+type JWTConfig struct {
+	Secret     string        `env:"JWT_SECRET" yaml:"secret"`
+	AccessTTL  time.Duration `env:"ACCESS_TOKEN_TTL" yaml:"access_ttl" default:"15m"`
+	RefreshTTL time.Duration `env:"REFRESH_TOKEN_TTL" yaml:"refresh_ttl" default:"168h"`
+}
+
+type MailConfig struct {
+	// smtp, file atau memory
+	Driver   string `env:"MAIL_DRIVER" yaml:"driver" default:"smtp"`
+	Sender   string `env:"EMAIL_SENDER" yaml:"sender"`
+	Password string `env:"APP_PASSWORD" yaml:"password"`
+	SMTPHost string `env:"SMTP_HOST" yaml:"smtp_host" default:"smtp.gmail.com"`
+	SMTPPort int    `env:"SMTP_PORT" yaml:"smtp_port" default:"587"`
+	SMTPTLS  string `env:"SMTP_TLS" yaml:"smtp_tls" default:"starttls"`
+	Dir      string `env:"MAIL_DIR" yaml:"dir" default:"mails"`
+}
+
+type WorkerConfig struct {
+	// false jika worker dijalankan terpisah lewat cmd/worker
+	RunInServer bool `env:"RUN_WORKER" yaml:"run_in_server" default:"true"`
+	Concurrency int  `env:"WORKER_CONCURRENCY" yaml:"concurrency" default:"4"`
+	MaxAttempts int  `env:"JOB_MAX_ATTEMPTS" yaml:"max_attempts" default:"5"`
+}
+
+type CacheConfig struct {
+	// cache L1 product di memori, 0 = mati
+	LocalSize int           `env:"LOCAL_CACHE_SIZE" yaml:"local_size" default:"0"`
+	LocalTTL  time.Duration `env:"LOCAL_CACHE_TTL" yaml:"local_ttl" default:"5s"`
+
+	WarmOnBoot      bool    `env:"WARM_CACHE_ON_BOOT" yaml:"warm_on_boot" default:"false"`
+	WarmupBatchSize int     `env:"WARMUP_BATCH_SIZE" yaml:"warmup_batch_size" default:"500"`
+	WarmupQPS       float64 `env:"WARMUP_QPS" yaml:"warmup_qps" default:"20"`
+	WarmupHotStores int     `env:"WARMUP_HOT_STORES" yaml:"warmup_hot_stores" default:"20"`
+}
+
+// Load membaca konfigurasi lalu memvalidasinya, dipanggil sekali di awal setiap binary
+func Load() (*Config, error) {
+	// .env tidak menimpa env yang sudah di-set
+	_ = godotenv.Load()
+
+	cfg := &Config{}
+	if err := walk(reflect.ValueOf(cfg).Elem(), setDefault); err != nil {
+		return nil, err
+	}
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := walk(reflect.ValueOf(cfg).Elem(), setEnv); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// loadFile menimpa nilai default dengan isi file yaml, key yang tidak ada di file tidak berubah
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config: %v", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config: %s: %v", path, err)
+	}
+
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// walk memanggil fn untuk setiap field daun, semua error dikumpulkan
+func walk(v reflect.Value, fn func(field reflect.StructField, value reflect.Value) error) error {
+	var errs []error
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			if err := walk(v.Field(i), fn); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		if err := fn(field, v.Field(i)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func setDefault(field reflect.StructField, value reflect.Value) error {
+	raw, ok := field.Tag.Lookup("default")
+	if !ok {
+		return nil
+	}
+	if err := set(value, raw); err != nil {
+		// tag default yang salah adalah bug, bukan kesalahan pengguna
+		panic(fmt.Sprintf("config: default %s: %v", field.Name, err))
+	}
+
+	return nil
+}
+
+func setEnv(field reflect.StructField, value reflect.Value) error {
+	name := field.Tag.Get("env")
+	raw := os.Getenv(name)
+	if name == "" || raw == "" {
+		return nil
+	}
+	if err := set(value, raw); err != nil {
+		return fmt.Errorf("config: %s: %v", name, err)
+	}
+
+	return nil
+}
+
+func set(field reflect.Value, raw string) error {
+	if field.Type() == durationType {
+		if raw == "" {
+			field.SetInt(0)
+			return nil
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q bukan angka", raw)
+		}
+		field.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%q bukan angka", raw)
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q bukan true/false", raw)
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("tipe %s tidak didukung", field.Type())
+	}
+
+	return nil
+}
+
+// Validate menolak konfigurasi yang membuat aplikasi jalan dalam keadaan salah
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("config: "+format, args...))
+		}
+	}
+
+	check(c.JWT.Secret != "", "JWT_SECRET wajib diisi")
+	check(c.JWT.AccessTTL > 0, "ACCESS_TOKEN_TTL harus lebih dari 0")
+	check(c.JWT.RefreshTTL > c.JWT.AccessTTL, "REFRESH_TOKEN_TTL harus lebih lama dari ACCESS_TOKEN_TTL")
+	check(c.Database.Name != "", "DB_NAME wajib diisi")
+	check(c.Database.Host != "", "DB_HOST wajib diisi")
+	check(c.Database.User != "", "DB_USER wajib diisi")
+	check(c.Redis.Addr != "", "REDIS_ADDR wajib diisi")
+
+	if u, err := url.Parse(c.App.ResetURL); err != nil || u.Scheme == "" || u.Host == "" {
+		check(false, "RESET_URL %q harus url lengkap", c.App.ResetURL)
+	}
+
+	check(c.App.Port > 0 && c.App.Port <= 65535, "PORT %d tidak valid", c.App.Port)
+	check(c.Database.Port > 0 && c.Database.Port <= 65535, "DB_PORT %d tidak valid", c.Database.Port)
+	check(c.App.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT harus lebih dari 0")
+
+	switch strings.ToLower(c.Mail.Driver) {
+	case "smtp":
+		check(c.Mail.Sender != "", "EMAIL_SENDER wajib diisi untuk MAIL_DRIVER=smtp")
+		check(c.Mail.Password != "", "APP_PASSWORD wajib diisi untuk MAIL_DRIVER=smtp")
+		check(c.Mail.SMTPHost != "", "SMTP_HOST wajib diisi untuk MAIL_DRIVER=smtp")
+		check(c.Mail.SMTPPort > 0 && c.Mail.SMTPPort <= 65535, "SMTP_PORT %d tidak valid", c.Mail.SMTPPort)
+	case "file", "memory":
+	default:
+		check(false, "MAIL_DRIVER %q tidak dikenal (smtp, file atau memory)", c.Mail.Driver)
+	}
+
+	check(c.Worker.Concurrency > 0, "WORKER_CONCURRENCY harus lebih dari 0")
+	check(c.Worker.MaxAttempts > 0, "JOB_MAX_ATTEMPTS harus lebih dari 0")
+
+	check(c.Cache.LocalSize >= 0, "LOCAL_CACHE_SIZE tidak boleh negatif")
+	check(c.Cache.LocalSize == 0 || c.Cache.LocalTTL > 0, "LOCAL_CACHE_TTL harus lebih dari 0")
+	check(c.Cache.WarmupBatchSize > 0, "WARMUP_BATCH_SIZE harus lebih dari 0")
+	check(c.Cache.WarmupQPS >= 0, "WARMUP_QPS tidak boleh negatif")
+
+	return errors.Join(errs...)
+}
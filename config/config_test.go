@@ -0,0 +1,147 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setRequired mengisi env wajib supaya Validate lolos, env yang kosong dianggap tidak diisi
+func setRequired(t *testing.T) {
+	t.Helper()
+	for key, value := range map[string]string{
+		"CONFIG_FILE":     "",
+		"JWT_SECRET":      "rahasia",
+		"DB_NAME":         "api_shope",
+		"MAIL_DRIVER":     "memory",
+		"PORT":            "",
+		"SMTP_PORT":       "",
+		"LOCAL_CACHE_TTL": "",
+	} {
+		t.Setenv(key, value)
+	}
+}
+
+func writeFile(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	setRequired(t)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.App.Port != 8080 || cfg.App.ShutdownTimeout != 30*time.Second {
+		t.Errorf("app = %+v", cfg.App)
+	}
+	if cfg.JWT.AccessTTL != 15*time.Minute || cfg.JWT.RefreshTTL != 7*24*time.Hour {
+		t.Errorf("jwt = %+v", cfg.JWT)
+	}
+	if cfg.Worker.Concurrency != 4 || !cfg.Worker.RunInServer {
+		t.Errorf("worker = %+v", cfg.Worker)
+	}
+	if got := cfg.Database.DSN(); !strings.HasPrefix(got, "root:@tcp(127.0.0.1:3306)/api_shope?") {
+		t.Errorf("dsn = %s", got)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	setRequired(t)
+	t.Setenv("CONFIG_FILE", writeFile(t, `
+app:
+  port: 9000
+  shutdown_timeout: 10s
+cache:
+  local_ttl: 2s # komentar
+worker:
+  concurrency: 8
+`))
+	t.Setenv("WORKER_CONCURRENCY", "16")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// file menimpa default
+	if cfg.App.Port != 9000 || cfg.App.ShutdownTimeout != 10*time.Second || cfg.Cache.LocalTTL != 2*time.Second {
+		t.Errorf("nilai file tidak terpakai: %+v %+v", cfg.App, cfg.Cache)
+	}
+	// env menimpa file
+	if cfg.Worker.Concurrency != 16 {
+		t.Errorf("concurrency = %d, ingin 16 dari env", cfg.Worker.Concurrency)
+	}
+	// key yang tidak ada di file tetap default
+	if cfg.Worker.MaxAttempts != 5 {
+		t.Errorf("max attempts = %d, ingin default 5", cfg.Worker.MaxAttempts)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		want string
+	}{
+		{name: "jwt secret kosong", env: map[string]string{"JWT_SECRET": ""}, want: "JWT_SECRET wajib diisi"},
+		{name: "key yaml salah ketik", file: "app:\n  prot: 9000\n", want: "field prot not found"},
+		{name: "section yaml tidak dikenal", file: "databse:\n  name: x\n", want: "field databse not found"},
+		{name: "tipe yaml salah", file: "worker:\n  concurrency: banyak\n", want: "banyak"},
+		{name: "env bukan angka", env: map[string]string{"WORKER_CONCURRENCY": "abc"}, want: "WORKER_CONCURRENCY"},
+		{name: "durasi env salah", env: map[string]string{"SHUTDOWN_TIMEOUT": "sebentar"}, want: "SHUTDOWN_TIMEOUT"},
+		{name: "port di luar rentang", env: map[string]string{"PORT": "70000"}, want: "PORT 70000 tidak valid"},
+		{name: "driver mail tidak dikenal", env: map[string]string{"MAIL_DRIVER": "pos"}, want: "MAIL_DRIVER"},
+		{
+			name: "smtp tanpa pengirim",
+			env:  map[string]string{"MAIL_DRIVER": "smtp", "EMAIL_SENDER": "", "APP_PASSWORD": ""},
+			want: "EMAIL_SENDER wajib diisi",
+		},
+		{
+			name: "refresh lebih pendek dari access",
+			env:  map[string]string{"ACCESS_TOKEN_TTL": "1h", "REFRESH_TOKEN_TTL": "30m"},
+			want: "REFRESH_TOKEN_TTL",
+		},
+		{name: "reset url relatif", env: map[string]string{"RESET_URL": "/reset"}, want: "RESET_URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequired(t)
+			if tt.file != "" {
+				t.Setenv("CONFIG_FILE", writeFile(t, tt.file))
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			_, err := Load()
+			if err == nil {
+				t.Fatalf("Load seharusnya gagal dengan %q", tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, ingin memuat %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadExampleFile(t *testing.T) {
+	setRequired(t)
+	t.Setenv("CONFIG_FILE", "config.example.yaml")
+
+	if _, err := Load(); err != nil {
+		t.Fatalf("config.example.yaml harus bisa dimuat: %v", err)
+	}
+}
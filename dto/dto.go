@@ -7,6 +7,7 @@ type RegisterReq struct {
 	Name     string `json:"username"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	Locale   string `json:"locale"`
 }
 
 type LoginReq struct {
@@ -14,6 +15,40 @@ type LoginReq struct {
 	Password string `json:"password"`
 }
 
+type ForgotPasswordReq struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordReq struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+type ChangePasswordReq struct {
+	UserID      uint   `json:"-"`
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+type RefreshReq struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// satu family = satu sesi login, diwariskan ke setiap refresh token hasil rotasi
+type RefreshSession struct {
+	UserID    uint
+	Email     string
+	Family    string
+	AccessJTI string
+	AccessExp time.Time
+}
+
 //shop
 
 //store
@@ -46,17 +81,22 @@ type JustStore struct {
 //product
 
 type CreateProductReq struct {
-	UserID  uint   `json:"-"`
-	StoreID uint   `json:"-"`
-	Name    string `json:"name"`
-	Stock   int    `json:"stock"`
+	UserID   uint   `json:"-"`
+	StoreID  uint   `json:"-"`
+	Name     string `json:"name"`
+	Stock    int    `json:"stock"`
+	Price    int64  `json:"price"`
+	Currency string `json:"currency"`
 }
 
+// Price dan Currency nil berarti tidak diubah
 type UpdateProductReq struct {
-	ID     uint   `json:"-"`
-	UserID uint   `json:"-"`
-	Name   string `json:"name"`
-	Stock  int    `json:"stock"`
+	ID       uint    `json:"-"`
+	UserID   uint    `json:"-"`
+	Name     string  `json:"name"`
+	Stock    int     `json:"stock"`
+	Price    *int64  `json:"price"`
+	Currency *string `json:"currency"`
 }
 
 type Product struct {
@@ -64,6 +104,8 @@ type Product struct {
 	ID        uint      `json:"id"`
 	Name      string    `json:"name"`
 	Stock     int       `json:"stock"`
+	Price     int64     `json:"price"`
+	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -78,6 +120,12 @@ type CartItem struct {
 	IsProductDeleted bool `json:"is_product_deleted"`
 }
 
+type CartTotal struct {
+	TotalItem  int    `json:"total_item"`
+	TotalPrice int64  `json:"total_price"`
+	Currency   string `json:"currency"`
+}
+
 type CreateCartItemReq struct {
 	UserID         uint `json:"-"`
 	ProductID      uint `json:"-"`
@@ -98,3 +146,30 @@ type UpdatePaidCartItemReq struct {
 	ProductID      uint   `json:"-"`
 	PurchaseAmount int    `json:"purchase_amount"`
 }
+
+//order
+type CheckoutReq struct {
+	UserID      uint   `json:"-"`
+	Email       string `json:"-"`
+	CartItemIDs []uint `json:"cart_item_ids"`
+}
+
+type OrderLine struct {
+	ID             uint   `json:"id"`
+	ProductID      uint   `json:"product_id"`
+	ProductName    string `json:"product_name"`
+	PurchaseAmount int    `json:"purchase_amount"`
+	UnitPrice      int64  `json:"unit_price"`
+	SubTotal       int64  `json:"sub_total"`
+}
+
+type Order struct {
+	ID         uint        `json:"id"`
+	UserID     uint        `json:"user_id"`
+	Status     string      `json:"status"`
+	TotalItem  int         `json:"total_item"`
+	TotalPrice int64       `json:"total_price"`
+	Currency   string      `json:"currency"`
+	CreatedAt  time.Time   `json:"created_at"`
+	Lines      []OrderLine `json:"lines"`
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"api_shope/internal/usecase"
+	"api_shope/utils/helper"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type AdminHandler struct {
+	adminUsecase usecase.AdminUsecase
+}
+
+func NewAdminHandler(adminUsecase usecase.AdminUsecase) *AdminHandler {
+	return &AdminHandler{adminUsecase}
+}
+
+// dead letter
+func (h *AdminHandler) ListDeadJobs(w http.ResponseWriter, r *http.Request) {
+	response, err := h.adminUsecase.ListDeadJobs()
+	if err != nil {
+		helper.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *AdminHandler) ReplayDeadJob(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	jobId := params["jobId"]
+	if jobId == "" {
+		helper.WriteError(w, http.StatusBadRequest, "params tidak ditemukan")
+		return
+	}
+
+	if err := h.adminUsecase.ReplayDeadJob(jobId); err != nil {
+		switch err {
+		case helper.ErrUnavaible:
+			helper.WriteError(w, http.StatusNotFound, "job tidak ditemukan")
+			return
+		default:
+			helper.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	helper.WriteJSON(w, http.StatusOK, nil)
+}
+
+// queue
+func (h *AdminHandler) GetQueueStats(w http.ResponseWriter, r *http.Request) {
+	response, err := h.adminUsecase.GetQueueStats()
+	if err != nil {
+		helper.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *AdminHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	jobId := params["jobId"]
+	if jobId == "" {
+		helper.WriteError(w, http.StatusBadRequest, "params tidak ditemukan")
+		return
+	}
+
+	response, err := h.adminUsecase.GetJob(jobId)
+	if err != nil {
+		switch err {
+		case helper.ErrUnavaible:
+			helper.WriteError(w, http.StatusNotFound, "job tidak ditemukan")
+			return
+		default:
+			helper.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	helper.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *AdminHandler) RetryJob(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	jobId := params["jobId"]
+	if jobId == "" {
+		helper.WriteError(w, http.StatusBadRequest, "params tidak ditemukan")
+		return
+	}
+
+	if err := h.adminUsecase.RetryJob(jobId); err != nil {
+		switch err {
+		case helper.ErrUnavaible:
+			helper.WriteError(w, http.StatusNotFound, "job tidak ditemukan")
+			return
+		case helper.ErrJobNotIdle:
+			helper.WriteError(w, http.StatusConflict, err.Error())
+			return
+		default:
+			helper.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	helper.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (h *AdminHandler) DiscardJob(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	jobId := params["jobId"]
+	if jobId == "" {
+		helper.WriteError(w, http.StatusBadRequest, "params tidak ditemukan")
+		return
+	}
+
+	if err := h.adminUsecase.DiscardJob(jobId); err != nil {
+		switch err {
+		case helper.ErrUnavaible:
+			helper.WriteError(w, http.StatusNotFound, "job tidak ditemukan")
+			return
+		case helper.ErrJobNotIdle:
+			helper.WriteError(w, http.StatusConflict, err.Error())
+			return
+		default:
+			helper.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	helper.WriteJSON(w, http.StatusOK, nil)
+}
+
+// cache
+func (h *AdminHandler) GetCacheMetrics(w http.ResponseWriter, r *http.Request) {
+	helper.WriteJSON(w, http.StatusOK, h.adminUsecase.GetCacheMetrics())
+}
+
+// mail
+func (h *AdminHandler) PreviewMail(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	response, err := h.adminUsecase.PreviewMail(params["template"], r.URL.Query().Get("locale"))
+	if err != nil {
+		switch err {
+		case helper.ErrUnavaible:
+			helper.WriteError(w, http.StatusNotFound, "template tidak ditemukan")
+			return
+		default:
+			helper.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "text":
+		w.Header().Set("content-type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Subject: " + response.Subject + "\n\n" + response.Text))
+	case "json":
+		helper.WriteJSON(w, http.StatusOK, response)
+	default:
+		w.Header().Set("content-type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(response.HTML))
+	}
+}
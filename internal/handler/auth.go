@@ -4,7 +4,9 @@ import (
 	"api_shope/dto"
 	"api_shope/internal/usecase"
 	"api_shope/utils/helper"
+	"api_shope/utils/middleware"
 	"encoding/json"
+	"io"
 	"net/http"
 )
 
@@ -23,7 +25,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	jwt, err := h.authUsecase.Login(&req)
+	response, err := h.authUsecase.Login(&req)
 	if err != nil {
 		switch err {
 		case helper.ErrInvalidEmail:
@@ -35,9 +37,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	helper.WriteJSON(w, http.StatusOK, map[string]string{
-		"token": jwt,
-	})
+	helper.WriteJSON(w, http.StatusOK, response)
 }
 
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -60,3 +60,171 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 	helper.WriteJSON(w, http.StatusOK, nil)
 }
+
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req dto.RefreshReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+
+	response, err := h.authUsecase.Refresh(&req)
+	if err != nil {
+		switch err {
+		case helper.ErrInvalidToken, helper.ErrTokenReused:
+			helper.WriteError(w, http.StatusUnauthorized, err.Error())
+			return
+		default:
+			helper.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	helper.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	claimsRaw := r.Context().Value(middleware.UserContextKey)
+	claims, ok := claimsRaw.(*helper.JWTCLAIMS)
+	if !ok {
+		helper.WriteError(w, http.StatusUnauthorized, "protected api")
+		return
+	}
+
+	// body boleh kosong, refresh token hanya dicabut jika dikirim
+	var req dto.RefreshReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		helper.WriteError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+
+	if err := h.authUsecase.Logout(claims, &req); err != nil {
+		switch err {
+		case helper.ErrInvalidToken:
+			helper.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		default:
+			helper.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	helper.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		helper.WriteError(w, http.StatusBadRequest, "token tidak ada")
+		return
+	}
+
+	if err := h.authUsecase.VerifyEmail(token); err != nil {
+		switch err {
+		case helper.ErrInvalidToken:
+			helper.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		default:
+			helper.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	helper.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	claimsRaw := r.Context().Value(middleware.UserContextKey)
+	claims, ok := claimsRaw.(*helper.JWTCLAIMS)
+	if !ok {
+		helper.WriteError(w, http.StatusUnauthorized, "protected api")
+		return
+	}
+
+	if err := h.authUsecase.ResendVerification(claims.UserID); err != nil {
+		switch err {
+		case helper.ErrAlreadyVerified:
+			helper.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		case helper.ErrTooManyRequest:
+			helper.WriteError(w, http.StatusTooManyRequests, err.Error())
+			return
+		default:
+			helper.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	helper.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req dto.ForgotPasswordReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+
+	if err := h.authUsecase.ForgotPassword(&req); err != nil {
+		switch err {
+		case helper.ErrInvalidEmail:
+			helper.WriteError(w, http.StatusBadRequest, "invalid email")
+			return
+		default:
+			helper.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	helper.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req dto.ResetPasswordReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+
+	if err := h.authUsecase.ResetPassword(&req); err != nil {
+		switch err {
+		case helper.ErrInvalidToken, helper.ErrWeakPassword:
+			helper.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		default:
+			helper.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	helper.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	claimsRaw := r.Context().Value(middleware.UserContextKey)
+	claims, ok := claimsRaw.(*helper.JWTCLAIMS)
+	if !ok {
+		helper.WriteError(w, http.StatusUnauthorized, "protected api")
+		return
+	}
+
+	var req dto.ChangePasswordReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+
+	req.UserID = claims.UserID
+	if err := h.authUsecase.ChangePassword(&req); err != nil {
+		switch err {
+		case helper.ErrWrongPassword, helper.ErrWeakPassword:
+			helper.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		default:
+			helper.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	helper.WriteJSON(w, http.StatusOK, nil)
+}
@@ -186,6 +186,9 @@ func (h *ShopHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 		case helper.ErrNotAdmin:
 			helper.WriteError(w, http.StatusUnauthorized, "bukan admin")
 			return
+		case helper.ErrInvalidPrice, helper.ErrInvalidCurrency:
+			helper.WriteError(w, http.StatusBadRequest, err.Error())
+			return
 		default:
 			helper.WriteError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -228,6 +231,9 @@ func (h *ShopHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 		case helper.ErrNotAdmin:
 			helper.WriteError(w, http.StatusUnauthorized, "bukan admin")
 			return
+		case helper.ErrInvalidPrice, helper.ErrInvalidCurrency, helper.ErrPriceRequired:
+			helper.WriteError(w, http.StatusBadRequest, err.Error())
+			return
 		default:
 			helper.WriteError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -335,6 +341,32 @@ func (h *ShopHandler) GetMyCartItems(w http.ResponseWriter, r *http.Request) {
 	helper.WriteJSON(w, http.StatusOK, response)
 }
 
+func (h *ShopHandler) GetMyCartTotal(w http.ResponseWriter, r *http.Request) {
+	claimsRaw := r.Context().Value(middleware.UserContextKey)
+	claims, ok := claimsRaw.(*helper.JWTCLAIMS)
+	if !ok {
+		helper.WriteError(w, http.StatusUnauthorized, "protected api")
+		return
+	}
+
+	response, err := h.shopUsecase.GetMyCartTotal(claims.UserID)
+	if err != nil {
+		switch err {
+		case helper.ErrUnavaible:
+			helper.WriteError(w, http.StatusOK, "kau belum ada cart items")
+			return
+		case helper.ErrMixedCurrency, helper.ErrMoneyOverflow:
+			helper.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		default:
+			helper.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	helper.WriteJSON(w, http.StatusOK, response)
+}
+
 func (h *ShopHandler) CreateCartItem(w http.ResponseWriter, r *http.Request) {
 	claimsRaw := r.Context().Value(middleware.UserContextKey)
 	claims, ok := claimsRaw.(*helper.JWTCLAIMS)
@@ -442,9 +474,15 @@ func (h *ShopHandler) UpdatePaidCartItem(w http.ResponseWriter, r *http.Request)
 	req.ProductID = uint(paramsProductId)
 	if err := h.shopUsecase.UpdatePaidCartItem(&req); err != nil {
 		switch err {
+		case helper.ErrEmailNotVerified:
+			helper.WriteError(w, http.StatusForbidden, err.Error())
+			return
 		case helper.ErrStocknotEnough:
 			helper.WriteError(w, http.StatusBadRequest, "stock tak cukup")
 			return
+		case helper.ErrInvalidCartItem:
+			helper.WriteError(w, http.StatusBadRequest, err.Error())
+			return
 		default:
 			helper.WriteError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -474,3 +512,94 @@ func (h *ShopHandler) DeleteCartItem(w http.ResponseWriter, r *http.Request) {
 
 	helper.WriteJSON(w, http.StatusOK, nil)
 }
+
+// order
+func (h *ShopHandler) Checkout(w http.ResponseWriter, r *http.Request) {
+	claimsRaw := r.Context().Value(middleware.UserContextKey)
+	claims, ok := claimsRaw.(*helper.JWTCLAIMS)
+	if !ok {
+		helper.WriteError(w, http.StatusUnauthorized, "protected api")
+		return
+	}
+
+	var req dto.CheckoutReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+
+	req.UserID = claims.UserID
+	req.Email = claims.Email
+	response, err := h.shopUsecase.Checkout(&req)
+	if err != nil {
+		switch err {
+		case helper.ErrEmailNotVerified:
+			helper.WriteError(w, http.StatusForbidden, err.Error())
+			return
+		case helper.ErrEmptyCheckout, helper.ErrInvalidCartItem, helper.ErrMixedCurrency, helper.ErrMoneyOverflow:
+			helper.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		case helper.ErrStocknotEnough:
+			helper.WriteError(w, http.StatusBadRequest, "stock tak cukup")
+			return
+		default:
+			helper.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	helper.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *ShopHandler) GetMyOrders(w http.ResponseWriter, r *http.Request) {
+	claimsRaw := r.Context().Value(middleware.UserContextKey)
+	claims, ok := claimsRaw.(*helper.JWTCLAIMS)
+	if !ok {
+		helper.WriteError(w, http.StatusUnauthorized, "protected api")
+		return
+	}
+
+	response, err := h.shopUsecase.GetMyOrders(claims.UserID)
+	if err != nil {
+		switch err {
+		case helper.ErrUnavaible:
+			helper.WriteError(w, http.StatusOK, "kau belum ada order")
+			return
+		default:
+			helper.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	helper.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *ShopHandler) GetMyOrder(w http.ResponseWriter, r *http.Request) {
+	claimsRaw := r.Context().Value(middleware.UserContextKey)
+	claims, ok := claimsRaw.(*helper.JWTCLAIMS)
+	if !ok {
+		helper.WriteError(w, http.StatusUnauthorized, "protected api")
+		return
+	}
+
+	params := mux.Vars(r)
+	paramsOrderId, err := strconv.Atoi(params["orderId"])
+	if err != nil {
+		helper.WriteError(w, http.StatusBadRequest, "params tidak ditemukan")
+		return
+	}
+
+	response, err := h.shopUsecase.GetMyOrder(claims.UserID, uint(paramsOrderId))
+	if err != nil {
+		switch err {
+		case helper.ErrUnavaible:
+			helper.WriteError(w, http.StatusNotFound, "order tidak ditemukan")
+			return
+		default:
+			helper.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	helper.WriteJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,390 @@
+package migrate
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	tableName = "schema_migrations"
+
+	// lock mysql (GET_LOCK) berlaku per koneksi, jadi semua langkah dijalankan di satu koneksi
+	lockName    = "api_shope:schema_migrations"
+	lockTimeout = 30 // detik
+)
+
+var (
+	ErrLocked       = errors.New("migrate: migrasi lain sedang berjalan")
+	ErrNoDown       = errors.New("migrate: migrasi tidak punya file down")
+	ErrNothingToRun = errors.New("migrate: tidak ada migrasi untuk dijalankan")
+
+	fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+	safeName = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// Migration adalah satu pasang file <versi>_<nama>.up.sql dan .down.sql.
+// Down kosong atau hanya berisi komentar berarti migrasi tidak bisa di-rollback.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Reversible bernilai false jika file down tidak berisi statement apa pun,
+// misalnya file hasil Create yang belum diisi
+func (m Migration) Reversible() bool {
+	return len(splitStatements(m.Down)) > 0
+}
+
+// Status adalah keadaan satu migrasi di database, AppliedAt nil berarti belum dijalankan
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	// Missing berarti versi tercatat di database tapi file-nya tidak ada
+	Missing bool
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+func New(db *gorm.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Load membaca semua file migrasi di root fsys, urut versi
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migrate: versi %d dipakai oleh %s dan %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, fmt.Errorf("migrate: %04d_%s tidak punya file up", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// withLock menjalankan fn di satu koneksi yang memegang lock migrasi,
+// deploy lain yang menjalankan migrasi bersamaan akan menunggu lalu gagal dengan ErrLocked
+func (m *Migrator) withLock(fn func(conn *gorm.DB) error) error {
+	return m.db.Connection(func(conn *gorm.DB) error {
+		var got sql.NullInt64
+		if err := conn.Raw("SELECT GET_LOCK(?, ?)", lockName, lockTimeout).Scan(&got).Error; err != nil {
+			return err
+		}
+		if !got.Valid || got.Int64 != 1 {
+			return ErrLocked
+		}
+		defer conn.Exec("SELECT RELEASE_LOCK(?)", lockName)
+
+		if err := ensureTable(conn); err != nil {
+			return err
+		}
+
+		return fn(conn)
+	})
+}
+
+func ensureTable(conn *gorm.DB) error {
+	return conn.Exec("CREATE TABLE IF NOT EXISTS `" + tableName + "` (" +
+		"`version` bigint NOT NULL, " +
+		"`name` varchar(255) NOT NULL, " +
+		"`applied_at` datetime(3) NOT NULL, " +
+		"PRIMARY KEY (`version`))").Error
+}
+
+type appliedRow struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time
+}
+
+func applied(conn *gorm.DB) ([]appliedRow, error) {
+	var rows []appliedRow
+	if err := conn.Table(tableName).Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// Up menjalankan migrasi yang belum dijalankan, steps <= 0 berarti semuanya
+func (m *Migrator) Up(steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(func(conn *gorm.DB) (err error) {
+		done, err = m.up(conn, steps)
+		return err
+	})
+
+	return done, err
+}
+
+func (m *Migrator) up(conn *gorm.DB, steps int) ([]Migration, error) {
+	rows, err := applied(conn)
+	if err != nil {
+		return nil, err
+	}
+	isApplied := make(map[int64]bool, len(rows))
+	for _, row := range rows {
+		isApplied[row.Version] = true
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		if isApplied[migration.Version] {
+			continue
+		}
+		if steps > 0 && len(done) == steps {
+			break
+		}
+
+		if err := run(conn, migration, migration.Up, true); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Down me-rollback migrasi terakhir sebanyak steps (minimal satu)
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+
+	var done []Migration
+	err := m.withLock(func(conn *gorm.DB) (err error) {
+		done, err = m.down(conn, steps)
+		return err
+	})
+
+	return done, err
+}
+
+func (m *Migrator) down(conn *gorm.DB, steps int) ([]Migration, error) {
+	rows, err := applied(conn)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrNothingToRun
+	}
+
+	var done []Migration
+	for i := len(rows) - 1; i >= 0 && len(done) < steps; i-- {
+		migration, ok := m.find(rows[i].Version)
+		if !ok {
+			return done, fmt.Errorf("migrate: file untuk versi %04d_%s tidak ada", rows[i].Version, rows[i].Name)
+		}
+		if !migration.Reversible() {
+			return done, fmt.Errorf("%w: %04d_%s", ErrNoDown, migration.Version, migration.Name)
+		}
+
+		if err := run(conn, migration, migration.Down, false); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Redo me-rollback lalu menjalankan ulang migrasi terakhir, keduanya di bawah lock yang sama
+func (m *Migrator) Redo() (*Migration, error) {
+	var migration Migration
+	err := m.withLock(func(conn *gorm.DB) error {
+		done, err := m.down(conn, 1)
+		if err != nil {
+			return err
+		}
+
+		migration = done[0]
+		return run(conn, migration, migration.Up, true)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &migration, nil
+}
+
+// Status mengembalikan semua migrasi yang dikenal beserta versi di database yang
+// file-nya hilang. tidak memakai lock supaya tetap bisa dilihat saat migrasi berjalan.
+func (m *Migrator) Status() ([]Status, error) {
+	if err := ensureTable(m.db); err != nil {
+		return nil, err
+	}
+	rows, err := applied(m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	appliedAt := make(map[int64]time.Time, len(rows))
+	for _, row := range rows {
+		appliedAt[row.Version] = row.AppliedAt
+		if _, ok := m.find(row.Version); !ok {
+			at := row.AppliedAt
+			statuses = append(statuses, Status{Version: row.Version, Name: row.Name, AppliedAt: &at, Missing: true})
+		}
+	}
+
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if at, ok := appliedAt[migration.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+
+	sort.SliceStable(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+
+	return Migration{}, false
+}
+
+// run menjalankan isi file lalu mencatat/menghapus versinya dalam satu transaksi.
+// perhatikan DDL di mysql melakukan commit implisit, jadi migrasi yang gagal di tengah
+// bisa meninggalkan sebagian perubahan, buat satu perubahan skema per migrasi.
+func run(conn *gorm.DB, migration Migration, script string, up bool) error {
+	return conn.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range splitStatements(script) {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return fmt.Errorf("migrate: %04d_%s: %v", migration.Version, migration.Name, err)
+			}
+		}
+
+		if up {
+			return tx.Table(tableName).Create(map[string]interface{}{
+				"version":    migration.Version,
+				"name":       migration.Name,
+				"applied_at": time.Now(),
+			}).Error
+		}
+
+		return tx.Table(tableName).Where("version = ?", migration.Version).Delete(nil).Error
+	})
+}
+
+// splitStatements memecah file per statement. statement diakhiri ';' di akhir baris,
+// baris yang diawali '--' dianggap komentar.
+func splitStatements(script string) []string {
+	var (
+		stmts   []string
+		current strings.Builder
+	)
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		stmts = append(stmts, rest)
+	}
+
+	return stmts
+}
+
+// Create membuat pasangan file migrasi kosong dengan versi berikutnya di dir
+func Create(dir, name string) (string, string, error) {
+	name = strings.Trim(safeName.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", "", errors.New("migrate: nama migrasi kosong")
+	}
+
+	migrations, err := Load(os.DirFS(dir))
+	if err != nil {
+		return "", "", err
+	}
+
+	var version int64 = 1
+	if len(migrations) > 0 {
+		version = migrations[len(migrations)-1].Version + 1
+	}
+
+	base := fmt.Sprintf("%04d_%s", version, name)
+	up := filepath.Join(dir, base+".up.sql")
+	down := filepath.Join(dir, base+".down.sql")
+
+	if err := os.WriteFile(up, []byte("-- "+base+" up\n"), 0o644); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(down, []byte("-- "+base+" down\n"), 0o644); err != nil {
+		return "", "", err
+	}
+
+	return up, down, nil
+}
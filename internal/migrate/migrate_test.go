@@ -0,0 +1,157 @@
+package migrate
+
+import (
+	"api_shope/migrations"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{"kosong", "", nil},
+		{"hanya komentar", "-- 0002_x down\n\n  -- lagi\n", nil},
+		{"satu statement", "DROP TABLE a;", []string{"DROP TABLE a;"}},
+		{
+			"multi baris",
+			"-- buat tabel\nCREATE TABLE a (\n  id int\n);\n\nCREATE INDEX i ON a (id);\n",
+			[]string{"CREATE TABLE a (\n  id int\n);", "CREATE INDEX i ON a (id);"},
+		},
+		{"tanpa titik koma di akhir", "DROP TABLE a;\nDROP TABLE b", []string{"DROP TABLE a;", "DROP TABLE b"}},
+		{"titik koma di tengah baris", "INSERT INTO a VALUES ('x;y');", []string{"INSERT INTO a VALUES ('x;y');"}},
+	}
+
+	for _, tt := range tests {
+		if got := splitStatements(tt.script); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: splitStatements = %q, ingin %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReversible(t *testing.T) {
+	tests := []struct {
+		down string
+		want bool
+	}{
+		{"", false},
+		{"   \n", false},
+		{"-- 0002_add_index down\n", false},
+		{"DROP INDEX i ON a;", true},
+	}
+
+	for _, tt := range tests {
+		if got := (Migration{Down: tt.down}).Reversible(); got != tt.want {
+			t.Errorf("Reversible(%q) = %v, ingin %v", tt.down, got, tt.want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_index.up.sql":   {Data: []byte("CREATE INDEX i ON a (id);")},
+		"0001_init.up.sql":        {Data: []byte("CREATE TABLE a (id int);")},
+		"0001_init.down.sql":      {Data: []byte("DROP TABLE a;")},
+		"0010_later.up.sql":       {Data: []byte("SELECT 1;")},
+		"README.md":               {Data: []byte("bukan migrasi")},
+		"0003_Bad-Name.up.sql":    {Data: []byte("SELECT 1;")},
+		"nested/0004_x.up.sql":    {Data: []byte("SELECT 1;")},
+		"0005_no_ext.up.sql.orig": {Data: []byte("SELECT 1;")},
+	}
+
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []int64
+	for _, m := range migrations {
+		got = append(got, m.Version)
+	}
+	if want := []int64{1, 2, 10}; !slices.Equal(got, want) {
+		t.Fatalf("versi = %v, ingin %v", got, want)
+	}
+	if migrations[0].Name != "init" || !migrations[0].Reversible() {
+		t.Errorf("0001 = %+v", migrations[0])
+	}
+	if migrations[1].Reversible() {
+		t.Errorf("0002 tanpa file down seharusnya tidak reversible")
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{"tanpa up", fstest.MapFS{"0001_init.down.sql": {Data: []byte("DROP TABLE a;")}}},
+		{"up kosong", fstest.MapFS{"0001_init.up.sql": {Data: []byte("  \n")}}},
+		{"versi bentrok", fstest.MapFS{
+			"0001_init.up.sql":  {Data: []byte("SELECT 1;")},
+			"0001_other.up.sql": {Data: []byte("SELECT 1;")},
+		}},
+	}
+
+	for _, tt := range tests {
+		if _, err := Load(tt.fsys); err == nil {
+			t.Errorf("%s: Load seharusnya gagal", tt.name)
+		}
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+
+	up, down, err := Create(dir, "Add Index!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(up) != "0001_add_index.up.sql" || filepath.Base(down) != "0001_add_index.down.sql" {
+		t.Fatalf("nama file = %s, %s", up, down)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "0007_manual.up.sql"), []byte("SELECT 1;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	up, _, err = Create(dir, "next")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(up) != "0008_next.up.sql" {
+		t.Errorf("versi berikutnya = %s, ingin 0008_next.up.sql", filepath.Base(up))
+	}
+
+	// file down hasil Create hanya berisi komentar, jadi tidak bisa dipakai rollback
+	migrations, err := Load(os.DirFS(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrations[0].Reversible() {
+		t.Errorf("down hasil Create seharusnya tidak reversible")
+	}
+
+	if _, _, err := Create(dir, "!!!"); err == nil {
+		t.Errorf("nama kosong seharusnya ditolak")
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	all, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, m := range all {
+		if m.Version != int64(i+1) {
+			t.Errorf("versi %d di posisi %d, ada versi yang terlewat", m.Version, i)
+		}
+		if !m.Reversible() {
+			t.Errorf("%04d_%s tidak punya down", m.Version, m.Name)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package queue
+
+import (
+	"api_shope/utils/helper"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// hash job id -> DeadJob, berisi job yang gagal permanen
+const DeadKey = "behind:dead"
+
+type DeadJob struct {
+	Job      Job       `json:"job"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+func AddDead(ctx context.Context, c redis.Cmdable, job *Job, reason string) error {
+	data, err := json.Marshal(DeadJob{
+		Job:      *job,
+		Error:    reason,
+		FailedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("queue: %v", err)
+	}
+
+	if err := c.HSet(ctx, DeadKey, job.ID, data).Err(); err != nil {
+		return err
+	}
+
+	return SetStatus(ctx, c, job, StatusDead, reason, time.Time{})
+}
+
+// ListDead mengembalikan dead job, yang terbaru lebih dulu
+func ListDead(ctx context.Context, c redis.Cmdable) ([]DeadJob, error) {
+	data, err := c.HGetAll(ctx, DeadKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]DeadJob, 0, len(data))
+	for _, raw := range data {
+		var dead DeadJob
+		if err := json.Unmarshal([]byte(raw), &dead); err != nil {
+			continue
+		}
+		jobs = append(jobs, dead)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].FailedAt.After(jobs[j].FailedAt) })
+	return jobs, nil
+}
+
+// replayDeadScript memindahkan dead job ke stream secara atomik, replay bersamaan
+// atas job yang sama hanya memasukkan satu kali
+var replayDeadScript = redis.NewScript(`
+local raw = redis.call("HGET", KEYS[1], ARGV[1])
+if not raw then
+	return false
+end
+redis.call("HDEL", KEYS[1], ARGV[1])
+local dead = cjson.decode(raw)
+redis.call("XADD", KEYS[2], "*",
+	"id", dead.job.id, "op", dead.job.op, "payload", dead.job.payload, "attempt", "0")
+return raw
+`)
+
+// ReplayDead memasukkan kembali dead job ke stream dengan attempt direset
+func ReplayDead(ctx context.Context, c redis.Cmdable, id string) error {
+	raw, err := replayDeadScript.Run(ctx, c, []string{DeadKey, StreamKey}, id).Text()
+	if err == redis.Nil {
+		return helper.ErrUnavaible
+	}
+	if err != nil {
+		return err
+	}
+
+	var dead DeadJob
+	if err := json.Unmarshal([]byte(raw), &dead); err != nil {
+		return fmt.Errorf("queue: %v", err)
+	}
+
+	dead.Job.Attempt = 0
+	return SetStatus(ctx, c, &dead.Job, StatusQueued, "", time.Time{})
+}
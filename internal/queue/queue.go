@@ -0,0 +1,246 @@
+package queue
+
+import (
+	"api_shope/utils/helper"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// semua job masuk ke satu redis stream, dibaca worker lewat consumer group
+const (
+	StreamKey = "behind:stream:jobs"
+	GroupName = "behind:workers"
+
+	// sorted set job terjadwal dan job retry, score = unix ms waktu jalan
+	DelayedKey = "behind:delayed"
+	// hash job id -> member di DelayedKey, supaya job bisa dicari tanpa scan
+	DelayedIndexKey = "behind:delayed:index"
+)
+
+// Job adalah isi satu entry di stream
+type Job struct {
+	ID      string `json:"id"`
+	Op      string `json:"op"`
+	Payload string `json:"payload"`
+	Attempt int    `json:"attempt"`
+}
+
+// MailPayload adalah payload job yang berakhir dengan kirim email.
+// isi email dirender worker dari template sesuai locale dan Data.
+type MailPayload struct {
+	ID     uint                   `json:"id"`
+	Email  string                 `json:"email"`
+	Locale string                 `json:"locale"`
+	Data   map[string]interface{} `json:"data"`
+
+	// job lama yang belum memakai template
+	Message string `json:"message,omitempty"`
+}
+
+// UnmarshalJSON memakai UseNumber supaya angka di Data (nomor order, jumlah item) tetap
+// json.Number, bukan float64 yang tercetak sebagai 1.234567e+06 di template
+func (p *MailPayload) UnmarshalJSON(data []byte) error {
+	type plain MailPayload
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode((*plain)(p))
+}
+
+func NewJob(op string, payload interface{}) (*Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("queue: %v", err)
+	}
+
+	id, err := helper.GenerateRandomToken(12)
+	if err != nil {
+		return nil, fmt.Errorf("queue: %v", err)
+	}
+
+	return &Job{ID: id, Op: op, Payload: string(data)}, nil
+}
+
+// Enqueue menambahkan job ke stream. c bisa berupa client atau pipeline,
+// jadi job bisa ditulis bersamaan dengan perubahan cache lain.
+func Enqueue(ctx context.Context, c redis.Cmdable, op string, payload interface{}) error {
+	job, err := NewJob(op, payload)
+	if err != nil {
+		return err
+	}
+
+	if err := Push(ctx, c, job); err != nil {
+		return err
+	}
+
+	return SetStatus(ctx, c, job, StatusQueued, "", time.Time{})
+}
+
+// EnqueueAt menjadwalkan job untuk dijalankan pada runAt
+func EnqueueAt(ctx context.Context, c redis.Cmdable, op string, payload interface{}, runAt time.Time) error {
+	job, err := NewJob(op, payload)
+	if err != nil {
+		return err
+	}
+
+	if err := Delay(ctx, c, job, runAt); err != nil {
+		return err
+	}
+
+	return SetStatus(ctx, c, job, StatusQueued, "", runAt)
+}
+
+// EnqueueIn menjadwalkan job untuk dijalankan setelah delay
+func EnqueueIn(ctx context.Context, c redis.Cmdable, op string, payload interface{}, delay time.Duration) error {
+	return EnqueueAt(ctx, c, op, payload, time.Now().Add(delay))
+}
+
+func Push(ctx context.Context, c redis.Cmdable, job *Job) error {
+	return c.XAdd(ctx, &redis.XAddArgs{
+		Stream: StreamKey,
+		Values: map[string]interface{}{
+			"id":      job.ID,
+			"op":      job.Op,
+			"payload": job.Payload,
+			"attempt": job.Attempt,
+		},
+	}).Err()
+}
+
+// ParseMessage membaca kembali job dari entry stream
+func ParseMessage(msg redis.XMessage) *Job {
+	job := &Job{}
+	job.ID, _ = msg.Values["id"].(string)
+	job.Op, _ = msg.Values["op"].(string)
+	job.Payload, _ = msg.Values["payload"].(string)
+	if attempt, ok := msg.Values["attempt"].(string); ok {
+		job.Attempt, _ = strconv.Atoi(attempt)
+	}
+	if job.ID == "" {
+		job.ID = msg.ID
+	}
+
+	return job
+}
+
+// Delay menaruh job di sorted set, nanti dipindah ke stream oleh worker saat waktunya tiba
+func Delay(ctx context.Context, c redis.Cmdable, job *Job, runAt time.Time) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("queue: %v", err)
+	}
+
+	if err := c.ZAdd(ctx, DelayedKey, redis.Z{
+		Score:  float64(runAt.UnixMilli()),
+		Member: data,
+	}).Err(); err != nil {
+		return err
+	}
+
+	return c.HSet(ctx, DelayedIndexKey, job.ID, data).Err()
+}
+
+// promoteScript memindahkan job yang sudah jatuh tempo ke stream secara atomik,
+// jadi aman walau beberapa worker menjalankannya bersamaan
+var promoteScript = redis.NewScript(`
+local items = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, tonumber(ARGV[2]))
+for _, item in ipairs(items) do
+	if redis.call("ZREM", KEYS[1], item) == 1 then
+		local job = cjson.decode(item)
+		redis.call("HDEL", KEYS[3], job.id)
+		redis.call("XADD", KEYS[2], "*",
+			"id", job.id, "op", job.op, "payload", job.payload, "attempt", job.attempt)
+	end
+end
+return #items
+`)
+
+// PromoteDue mengembalikan jumlah job yang dipindahkan ke stream
+func PromoteDue(ctx context.Context, c redis.Scripter, now time.Time, limit int) (int, error) {
+	return promoteScript.Run(ctx, c, []string{DelayedKey, StreamKey, DelayedIndexKey}, now.UnixMilli(), limit).Int()
+}
+
+// pushOnceScript hanya menambahkan job jika dedupe key belum ada,
+// dipakai relay outbox supaya satu baris outbox tidak terkirim dua kali
+var pushOnceScript = redis.NewScript(`
+if redis.call("SET", KEYS[1], "1", "NX", "EX", tonumber(ARGV[1])) then
+	redis.call("XADD", KEYS[2], "*",
+		"id", ARGV[2], "op", ARGV[3], "payload", ARGV[4], "attempt", "0")
+	return 1
+end
+return 0
+`)
+
+// PushOnce mengembalikan false jika job dengan dedupeKey yang sama sudah pernah masuk
+func PushOnce(ctx context.Context, c redis.Scripter, job *Job, dedupeKey string, ttl time.Duration) (bool, error) {
+	added, err := pushOnceScript.Run(ctx, c, []string{dedupeKey, StreamKey},
+		int64(ttl.Seconds()), job.ID, job.Op, job.Payload).Int()
+	if err != nil {
+		return false, err
+	}
+	if added == 0 {
+		return false, nil
+	}
+
+	if c, ok := c.(redis.Cmdable); ok {
+		if err := SetStatus(ctx, c, job, StatusQueued, "", time.Time{}); err != nil {
+			return true, err
+		}
+	}
+
+	return true, nil
+}
+
+// Trim membuang entry stream yang sudah di-ack. XADD MAXLEN tidak melihat status ack,
+// jadi batasnya dihitung dari group: entry di bawah id pending tertua dan sudah pernah
+// dikirim ke group pasti sudah di-ack. entry yang pending atau belum dibaca tidak disentuh.
+func Trim(ctx context.Context, c redis.Cmdable) (int64, error) {
+	groups, err := c.XInfoGroups(ctx, StreamKey).Result()
+	if err != nil {
+		if strings.Contains(err.Error(), "no such key") {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	minID := ""
+	for _, group := range groups {
+		id := group.LastDeliveredID
+		if group.Pending > 0 {
+			pending, err := c.XPending(ctx, StreamKey, group.Name).Result()
+			if err != nil {
+				return 0, err
+			}
+			id = pending.Lower
+		}
+		if minID == "" || lessID(id, minID) {
+			minID = id
+		}
+	}
+	if minID == "" || minID == "0-0" {
+		return 0, nil
+	}
+
+	return c.XTrimMinID(ctx, StreamKey, minID).Result()
+}
+
+// lessID membandingkan dua id entry stream (<unix ms>-<seq>)
+func lessID(a, b string) bool {
+	aMs, aSeq, _ := strings.Cut(a, "-")
+	bMs, bSeq, _ := strings.Cut(b, "-")
+	am, _ := strconv.ParseUint(aMs, 10, 64)
+	bm, _ := strconv.ParseUint(bMs, 10, 64)
+	if am != bm {
+		return am < bm
+	}
+
+	as, _ := strconv.ParseUint(aSeq, 10, 64)
+	bs, _ := strconv.ParseUint(bSeq, 10, 64)
+	return as < bs
+}
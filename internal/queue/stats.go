@@ -0,0 +1,100 @@
+package queue
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// QueueStats adalah ringkasan satu queue. Depth = job yang menunggu,
+// Lag = umur job tertua yang seharusnya sudah jalan tapi belum diambil worker.
+type QueueStats struct {
+	Name    string  `json:"name"`
+	Key     string  `json:"key"`
+	Depth   int64   `json:"depth"`
+	Pending int64   `json:"pending"`
+	Due     int64   `json:"due"`
+	Lag     float64 `json:"lag_seconds"`
+}
+
+func Stats(ctx context.Context, c redis.Cmdable) ([]QueueStats, error) {
+	now := time.Now()
+
+	stream, err := streamStats(ctx, c, now)
+	if err != nil {
+		return nil, err
+	}
+
+	delayed := QueueStats{Name: "delayed", Key: DelayedKey}
+	if delayed.Depth, err = c.ZCard(ctx, DelayedKey).Result(); err != nil {
+		return nil, err
+	}
+	max := strconv.FormatInt(now.UnixMilli(), 10)
+	if delayed.Due, err = c.ZCount(ctx, DelayedKey, "-inf", max).Result(); err != nil {
+		return nil, err
+	}
+	if delayed.Due > 0 {
+		oldest, err := c.ZRangeWithScores(ctx, DelayedKey, 0, 0).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(oldest) > 0 {
+			delayed.Lag = now.Sub(time.UnixMilli(int64(oldest[0].Score))).Seconds()
+		}
+	}
+
+	dead := QueueStats{Name: "dead", Key: DeadKey}
+	if dead.Depth, err = c.HLen(ctx, DeadKey).Result(); err != nil {
+		return nil, err
+	}
+
+	return []QueueStats{*stream, delayed, dead}, nil
+}
+
+func streamStats(ctx context.Context, c redis.Cmdable, now time.Time) (*QueueStats, error) {
+	stats := &QueueStats{Name: "jobs", Key: StreamKey}
+
+	groups, err := c.XInfoGroups(ctx, StreamKey).Result()
+	if err != nil {
+		// stream belum dibuat, berarti belum ada job sama sekali
+		if strings.Contains(err.Error(), "no such key") {
+			return stats, nil
+		}
+		return nil, err
+	}
+
+	for _, group := range groups {
+		if group.Name != GroupName {
+			continue
+		}
+
+		stats.Pending = group.Pending
+		stats.Depth = group.Lag
+		if stats.Depth < 0 {
+			// redis lama tidak selalu bisa menghitung lag group
+			if stats.Depth, err = c.XLen(ctx, StreamKey).Result(); err != nil {
+				return nil, err
+			}
+		}
+
+		next, err := c.XRangeN(ctx, StreamKey, "("+group.LastDeliveredID, "+", 1).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(next) > 0 {
+			stats.Lag = now.Sub(streamIDTime(next[0].ID)).Seconds()
+		}
+	}
+	stats.Due = stats.Depth
+
+	return stats, nil
+}
+
+// streamIDTime membaca waktu dari id entry stream (<unix ms>-<seq>)
+func streamIDTime(id string) time.Time {
+	ms, _, _ := strings.Cut(id, "-")
+	return parseMilli(ms)
+}
@@ -0,0 +1,294 @@
+package queue
+
+import (
+	"api_shope/utils/helper"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusDead      = "dead"
+	StatusDiscarded = "discarded"
+
+	// hash status per job dan list riwayat attempt, keduanya kedaluwarsa setelah retensi
+	statusKey       = "behind:job:"
+	historySuffix   = ":history"
+	statusRetention = 7 * 24 * time.Hour
+)
+
+// id job berasal dari token hex, id outbox atau id entry stream
+var validJobID = regexp.MustCompile(`^[a-zA-Z0-9-]{1,64}$`)
+
+// JobStatus adalah status terakhir sebuah job beserta riwayat attempt-nya
+type JobStatus struct {
+	ID        string       `json:"id"`
+	Op        string       `json:"op"`
+	Status    string       `json:"status"`
+	Attempt   int          `json:"attempt"`
+	Payload   string       `json:"payload"`
+	Error     string       `json:"error,omitempty"`
+	RunAt     *time.Time   `json:"run_at,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+	History   []AttemptLog `json:"history"`
+}
+
+type AttemptLog struct {
+	Attempt    int       `json:"attempt"`
+	Consumer   string    `json:"consumer"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// SetStatus menulis status job. c bisa berupa pipeline supaya status ikut
+// tertulis atomik bersama perpindahan job antar queue.
+func SetStatus(ctx context.Context, c redis.Cmdable, job *Job, status, reason string, runAt time.Time) error {
+	key := statusKey + job.ID
+	now := time.Now()
+
+	fields := map[string]interface{}{
+		"id":         job.ID,
+		"op":         job.Op,
+		"status":     status,
+		"attempt":    job.Attempt,
+		"payload":    job.Payload,
+		"error":      reason,
+		"run_at":     "",
+		"updated_at": now.UnixMilli(),
+	}
+	if !runAt.IsZero() {
+		fields["run_at"] = runAt.UnixMilli()
+	}
+
+	if err := c.HSetNX(ctx, key, "created_at", now.UnixMilli()).Err(); err != nil {
+		return err
+	}
+	if err := c.HSet(ctx, key, fields).Err(); err != nil {
+		return err
+	}
+
+	return c.Expire(ctx, key, statusRetention).Err()
+}
+
+// AddAttempt mencatat hasil satu kali eksekusi job
+func AddAttempt(ctx context.Context, c redis.Cmdable, jobID string, log AttemptLog) error {
+	data, err := json.Marshal(log)
+	if err != nil {
+		return fmt.Errorf("queue: %v", err)
+	}
+
+	key := statusKey + jobID + historySuffix
+	if err := c.RPush(ctx, key, data).Err(); err != nil {
+		return err
+	}
+
+	return c.Expire(ctx, key, statusRetention).Err()
+}
+
+func GetStatus(ctx context.Context, c redis.Cmdable, id string) (*JobStatus, error) {
+	if !validJobID.MatchString(id) {
+		return nil, helper.ErrUnavaible
+	}
+
+	fields, err := c.HGetAll(ctx, statusKey+id).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, helper.ErrUnavaible
+	}
+
+	status := &JobStatus{
+		ID:        fields["id"],
+		Op:        fields["op"],
+		Status:    fields["status"],
+		Payload:   fields["payload"],
+		Error:     fields["error"],
+		CreatedAt: parseMilli(fields["created_at"]),
+		UpdatedAt: parseMilli(fields["updated_at"]),
+		History:   []AttemptLog{},
+	}
+	status.Attempt, _ = strconv.Atoi(fields["attempt"])
+	if fields["run_at"] != "" {
+		runAt := parseMilli(fields["run_at"])
+		status.RunAt = &runAt
+	}
+
+	history, err := c.LRange(ctx, statusKey+id+historySuffix, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, raw := range history {
+		var log AttemptLog
+		if err := json.Unmarshal([]byte(raw), &log); err != nil {
+			continue
+		}
+		status.History = append(status.History, log)
+	}
+
+	return status, nil
+}
+
+func parseMilli(value string) time.Time {
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return time.UnixMilli(ms)
+}
+
+// findDelayed mencari member sorted set delayed milik job id lewat index.
+// job yang masuk sebelum index ada dicari dengan scan dan dibandingkan setelah di-decode.
+func findDelayed(ctx context.Context, c redis.Cmdable, id string) (string, error) {
+	member, err := c.HGet(ctx, DelayedIndexKey, id).Result()
+	if err == nil {
+		return member, nil
+	}
+	if err != redis.Nil {
+		return "", err
+	}
+
+	var cursor uint64
+	for {
+		items, next, err := c.ZScan(ctx, DelayedKey, cursor, "", 100).Result()
+		if err != nil {
+			return "", err
+		}
+		// hasil ZSCAN berupa pasangan member, score
+		for i := 0; i < len(items); i += 2 {
+			var job Job
+			if json.Unmarshal([]byte(items[i]), &job) == nil && job.ID == id {
+				return items[i], nil
+			}
+		}
+
+		if next == 0 {
+			return "", helper.ErrUnavaible
+		}
+		cursor = next
+	}
+}
+
+// takeDelayedScript mengambil job dari sorted set delayed dan, jika ARGV[3] = "1",
+// langsung memasukkannya ke stream. hanya pemanggil yang berhasil ZREM yang mendapat
+// job, jadi tidak bisa dobel dengan promoteScript atau admin lain.
+var takeDelayedScript = redis.NewScript(`
+if redis.call("ZREM", KEYS[1], ARGV[2]) == 0 then
+	return 0
+end
+redis.call("HDEL", KEYS[2], ARGV[1])
+if ARGV[3] == "1" then
+	local job = cjson.decode(ARGV[2])
+	redis.call("XADD", KEYS[3], "*",
+		"id", job.id, "op", job.op, "payload", job.payload, "attempt", job.attempt)
+end
+return 1
+`)
+
+// takeDelayed mengembalikan job yang berhasil diambil dari delayed, push berarti langsung masuk stream
+func takeDelayed(ctx context.Context, c redis.Cmdable, id string, push bool) (*Job, error) {
+	member, err := findDelayed(ctx, c, id)
+	if errors.Is(err, helper.ErrUnavaible) {
+		return nil, jobStateError(ctx, c, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var job Job
+	if err := json.Unmarshal([]byte(member), &job); err != nil {
+		return nil, fmt.Errorf("queue: %v", err)
+	}
+
+	flag := "0"
+	if push {
+		flag = "1"
+	}
+	taken, err := takeDelayedScript.Run(ctx, c, []string{DelayedKey, DelayedIndexKey, StreamKey}, id, member, flag).Int()
+	if err != nil {
+		return nil, err
+	}
+	if taken == 0 {
+		// baru saja dipindah ke stream oleh worker atau diambil admin lain
+		return nil, jobStateError(ctx, c, id)
+	}
+
+	return &job, nil
+}
+
+// Retry menjalankan ulang job yang sudah dead atau sedang menunggu retry, sekarang juga
+func Retry(ctx context.Context, c redis.Cmdable, id string) error {
+	if !validJobID.MatchString(id) {
+		return helper.ErrUnavaible
+	}
+
+	err := ReplayDead(ctx, c, id)
+	if !errors.Is(err, helper.ErrUnavaible) {
+		return err
+	}
+
+	job, err := takeDelayed(ctx, c, id, true)
+	if err != nil {
+		return err
+	}
+
+	return SetStatus(ctx, c, job, StatusQueued, "", time.Time{})
+}
+
+// Discard membuang job yang dead atau sedang menunggu retry/jadwal
+func Discard(ctx context.Context, c redis.Cmdable, id string) error {
+	if !validJobID.MatchString(id) {
+		return helper.ErrUnavaible
+	}
+
+	raw, err := c.HGet(ctx, DeadKey, id).Result()
+	if err == nil {
+		var dead DeadJob
+		if err := json.Unmarshal([]byte(raw), &dead); err != nil {
+			return fmt.Errorf("queue: %v", err)
+		}
+
+		_, err = c.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HDel(ctx, DeadKey, id)
+			return SetStatus(ctx, pipe, &dead.Job, StatusDiscarded, "dibuang admin", time.Time{})
+		})
+		return err
+	}
+	if err != redis.Nil {
+		return err
+	}
+
+	job, err := takeDelayed(ctx, c, id, false)
+	if err != nil {
+		return err
+	}
+
+	return SetStatus(ctx, c, job, StatusDiscarded, "dibuang admin", time.Time{})
+}
+
+// jobStateError membedakan job yang tidak dikenal dengan job yang sedang antri/berjalan/selesai
+func jobStateError(ctx context.Context, c redis.Cmdable, id string) error {
+	exists, err := c.Exists(ctx, statusKey+id).Result()
+	if err != nil {
+		return err
+	}
+	if exists == 0 {
+		return helper.ErrUnavaible
+	}
+
+	return helper.ErrJobNotIdle
+}
@@ -2,8 +2,11 @@ package repository
 
 import (
 	"api_shope/dto"
+	"api_shope/internal/queue"
 	"api_shope/model"
+	"api_shope/utils/helper"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -11,48 +14,383 @@ import (
 )
 
 type AuthRepo interface {
-	Register(req *dto.RegisterReq) error
+	Register(req *dto.RegisterReq) (*model.User, error)
 	LoginEmail(email string) (*model.User, error)
+	GetUserByID(id uint) (*model.User, error)
+	EnqueueMail(op string, user *model.User, data map[string]interface{}) error
+
+	//verify email
+	SaveVerifyToken(userId uint, tokenHash string) error
+	ConsumeVerifyToken(userId uint, tokenHash string) (bool, error)
+	MarkVerified(userId uint) error
+	AllowResendVerify(userId uint) (bool, error)
+
+	//password
+	SaveResetToken(userId uint, tokenHash string) error
+	ConsumeResetToken(userId uint, tokenHash string) (bool, error)
+	UpdatePassword(userId uint, password string) error
+	RevokeAllSessions(userId uint) error
+
+	//token
+	SaveRefreshToken(tokenHash string, session *dto.RefreshSession) error
+	UseRefreshToken(tokenHash string) (*dto.RefreshSession, error)
+	GetRefreshSession(tokenHash string) (*dto.RefreshSession, error)
+	RevokeFamily(family string) error
+	RevokeAccessToken(jti string, exp time.Time) error
+	IsAccessTokenRevoked(claims *helper.JWTCLAIMS) (bool, error)
 }
 
 type authRepo struct {
 	db    *gorm.DB
 	redis *redis.Client
+
+	// umur session refresh dan access token, sama dengan yang dipakai helper.JWT
+	accessTTL  time.Duration
+	refreshTTL time.Duration
 }
 
-func NewAuthRepo(db *gorm.DB, redis *redis.Client) AuthRepo {
-	return &authRepo{db, redis}
+func NewAuthRepo(db *gorm.DB, redis *redis.Client, accessTTL, refreshTTL time.Duration) AuthRepo {
+	return &authRepo{db, redis, accessTTL, refreshTTL}
 }
 
-func (r *authRepo) Register(req *dto.RegisterReq) error {
+func (r *authRepo) Register(req *dto.RegisterReq) (*model.User, error) {
 	newUser := model.User{
 		Email:    req.Email,
 		Password: req.Password,
 		Username: req.Name,
+		Locale:   req.Locale,
 	}
 
-	key := fmt.Sprintf("behind:pending:register:%d", newUser.ID)
-	message := fmt.Sprintf("Welcome to go journey %v", newUser.Username)
-	_, err := r.redis.Pipelined(ctx, func(p redis.Pipeliner) error {
-		p.HSet(ctx, key, map[string]interface{}{
-			"id":      newUser.ID,
-			"email":   newUser.Email,
-			"message": message,
-			"op":      "register",
-		})
-		p.Expire(ctx, key, 10*time.Minute)
-		return nil
+	// email welcome lewat outbox, jadi hanya terkirim jika user benar-benar tersimpan
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.User{}).Create(&newUser).Error; err != nil {
+			return err
+		}
+
+		return addOutbox(tx, "register", mailPayload(&newUser, nil))
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return r.db.Model(&model.User{}).Create(&newUser).Error
+
+	return &newUser, nil
 }
 
 func (r *authRepo) LoginEmail(email string) (*model.User, error) {
 	var user model.User
-	if err := r.db.Model(&model.User{}).Select("id", "email", "password").Where("email = ?", email).First(&user).Error; err != nil {
+	if err := r.db.Model(&model.User{}).Select("id", "username", "email", "password", "locale").Where("email = ?", email).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
+
+func (r *authRepo) GetUserByID(id uint) (*model.User, error) {
+	var user model.User
+	if err := r.db.Model(&model.User{}).Select("id", "username", "email", "password", "verified", "locale").Where("id = ?", id).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// nama user selalu ikut di data template
+func mailPayload(user *model.User, data map[string]interface{}) queue.MailPayload {
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	data["Name"] = user.Username
+
+	return queue.MailPayload{
+		ID:     user.ID,
+		Email:  user.Email,
+		Locale: user.Locale,
+		Data:   data,
+	}
+}
+
+// EnqueueMail langsung mengantrikan email, untuk email yang tidak terikat perubahan data
+func (r *authRepo) EnqueueMail(op string, user *model.User, data map[string]interface{}) error {
+	if err := queue.Enqueue(ctx, r.redis, op, mailPayload(user, data)); err != nil {
+		return fmt.Errorf("redis: %v", err)
+	}
+
+	return nil
+}
+
+// token verifikasi dan reset disimpan dalam bentuk hash, satu user hanya punya satu token aktif
+const (
+	VerifyTokenTTL  = 24 * time.Hour
+	verifyResendTTL = time.Minute
+	ResetTokenTTL   = 30 * time.Minute
+)
+
+func verifyTokenKey(userId uint) string {
+	return fmt.Sprintf("verify:email:%d", userId)
+}
+
+func resetTokenKey(userId uint) string {
+	return fmt.Sprintf("reset:password:%d", userId)
+}
+
+// hapus token hanya jika cocok, supaya token sekali pakai
+var consumeTokenScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+func (r *authRepo) saveOneTimeToken(key, tokenHash string, ttl time.Duration) error {
+	if err := r.redis.Set(ctx, key, tokenHash, ttl).Err(); err != nil {
+		return fmt.Errorf("redis: %v", err)
+	}
+
+	return nil
+}
+
+func (r *authRepo) consumeOneTimeToken(key, tokenHash string) (bool, error) {
+	deleted, err := consumeTokenScript.Run(ctx, r.redis, []string{key}, tokenHash).Int()
+	if err != nil {
+		return false, fmt.Errorf("redis: %v", err)
+	}
+
+	return deleted > 0, nil
+}
+
+func (r *authRepo) SaveVerifyToken(userId uint, tokenHash string) error {
+	return r.saveOneTimeToken(verifyTokenKey(userId), tokenHash, VerifyTokenTTL)
+}
+
+func (r *authRepo) ConsumeVerifyToken(userId uint, tokenHash string) (bool, error) {
+	return r.consumeOneTimeToken(verifyTokenKey(userId), tokenHash)
+}
+
+func (r *authRepo) SaveResetToken(userId uint, tokenHash string) error {
+	return r.saveOneTimeToken(resetTokenKey(userId), tokenHash, ResetTokenTTL)
+}
+
+func (r *authRepo) ConsumeResetToken(userId uint, tokenHash string) (bool, error) {
+	return r.consumeOneTimeToken(resetTokenKey(userId), tokenHash)
+}
+
+func (r *authRepo) UpdatePassword(userId uint, password string) error {
+	return r.db.Model(&model.User{}).Where("id = ?", userId).Update("password", password).Error
+}
+
+func (r *authRepo) MarkVerified(userId uint) error {
+	return r.db.Model(&model.User{}).Where("id = ?", userId).Update("verified", true).Error
+}
+
+func (r *authRepo) AllowResendVerify(userId uint) (bool, error) {
+	ok, err := r.redis.SetNX(ctx, fmt.Sprintf("verify:resend:%d", userId), 1, verifyResendTTL).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis: %v", err)
+	}
+
+	return ok, nil
+}
+
+// refresh token disimpan per hash token, family menandai satu sesi login.
+// token yang sudah dirotasi tetap disimpan dengan used=1 untuk deteksi reuse.
+func refreshTokenKey(tokenHash string) string {
+	return fmt.Sprintf("refresh:token:%s", tokenHash)
+}
+
+func refreshFamilyKey(family string) string {
+	return fmt.Sprintf("refresh:family:%s", family)
+}
+
+func refreshUserKey(userId uint) string {
+	return fmt.Sprintf("refresh:user:%d", userId)
+}
+
+func denylistKey(jti string) string {
+	return fmt.Sprintf("jwt:denylist:%s", jti)
+}
+
+// access token user yang diterbitkan sebelum waktu ini dianggap dicabut
+func revokedBeforeKey(userId uint) string {
+	return fmt.Sprintf("jwt:revoked_before:%d", userId)
+}
+
+// KEYS[1] token, KEYS[2] family, ARGV[1] family yang dibaca sebelumnya.
+// return 0 jika token tidak ada / family sudah dicabut,
+// 2 jika token sudah pernah dipakai, 1 jika berhasil
+var useRefreshTokenScript = redis.NewScript(`
+if redis.call("HGET", KEYS[1], "family") ~= ARGV[1] then
+	return 0
+end
+if redis.call("EXISTS", KEYS[2]) == 0 then
+	return 0
+end
+if redis.call("HGET", KEYS[1], "used") == "1" then
+	return 2
+end
+redis.call("HSET", KEYS[1], "used", "1")
+return 1
+`)
+
+func (r *authRepo) SaveRefreshToken(tokenHash string, session *dto.RefreshSession) error {
+	key := refreshTokenKey(tokenHash)
+	familyKey := refreshFamilyKey(session.Family)
+	userKey := refreshUserKey(session.UserID)
+
+	_, err := r.redis.Pipelined(ctx, func(p redis.Pipeliner) error {
+		p.HSet(ctx, key, map[string]interface{}{
+			"user_id": session.UserID,
+			"email":   session.Email,
+			"family":  session.Family,
+			"used":    0,
+		})
+		p.Expire(ctx, key, r.refreshTTL)
+		p.HSet(ctx, familyKey, map[string]interface{}{
+			"user_id":    session.UserID,
+			"access_jti": session.AccessJTI,
+			"access_exp": session.AccessExp.Unix(),
+		})
+		p.Expire(ctx, familyKey, r.refreshTTL)
+		p.SAdd(ctx, userKey, session.Family)
+		p.Expire(ctx, userKey, r.refreshTTL)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis: %v", err)
+	}
+
+	return nil
+}
+
+func (r *authRepo) UseRefreshToken(tokenHash string) (*dto.RefreshSession, error) {
+	key := refreshTokenKey(tokenHash)
+
+	// family dibaca dulu agar key family bisa dikirim lewat KEYS,
+	// script memastikan family token tidak berubah sejak dibaca
+	family, err := r.redis.HGet(ctx, key, "family").Result()
+	if err == redis.Nil {
+		return nil, helper.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis: %v", err)
+	}
+
+	status, err := useRefreshTokenScript.Run(ctx, r.redis, []string{key, refreshFamilyKey(family)}, family).Int64()
+	if err != nil {
+		return nil, fmt.Errorf("redis: %v", err)
+	}
+
+	switch status {
+	case 0:
+		return nil, helper.ErrInvalidToken
+	case 2:
+		if err := r.RevokeFamily(family); err != nil {
+			return nil, err
+		}
+		return nil, helper.ErrTokenReused
+	}
+
+	return r.GetRefreshSession(tokenHash)
+}
+
+func (r *authRepo) GetRefreshSession(tokenHash string) (*dto.RefreshSession, error) {
+	data, err := r.redis.HGetAll(ctx, refreshTokenKey(tokenHash)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis: %v", err)
+	}
+	if len(data) == 0 {
+		return nil, helper.ErrInvalidToken
+	}
+
+	userId, err := strconv.ParseUint(data["user_id"], 10, 64)
+	if err != nil {
+		return nil, helper.ErrInvalidToken
+	}
+
+	return &dto.RefreshSession{
+		UserID: uint(userId),
+		Email:  data["email"],
+		Family: data["family"],
+	}, nil
+}
+
+// mencabut satu sesi: semua refresh token family ini dan access token terakhirnya
+func (r *authRepo) RevokeFamily(family string) error {
+	familyKey := refreshFamilyKey(family)
+	data, err := r.redis.HGetAll(ctx, familyKey).Result()
+	if err != nil {
+		return fmt.Errorf("redis: %v", err)
+	}
+
+	if jti := data["access_jti"]; jti != "" {
+		exp, _ := strconv.ParseInt(data["access_exp"], 10, 64)
+		if err := r.RevokeAccessToken(jti, time.Unix(exp, 0)); err != nil {
+			return err
+		}
+	}
+
+	if err := r.redis.Del(ctx, familyKey).Err(); err != nil {
+		return fmt.Errorf("redis: %v", err)
+	}
+
+	return nil
+}
+
+func (r *authRepo) RevokeAccessToken(jti string, exp time.Time) error {
+	ttl := time.Until(exp)
+	if ttl <= 0 {
+		return nil
+	}
+
+	if err := r.redis.Set(ctx, denylistKey(jti), 1, ttl).Err(); err != nil {
+		return fmt.Errorf("redis: %v", err)
+	}
+
+	return nil
+}
+
+// mencabut semua sesi user, dipakai setelah password diganti
+func (r *authRepo) RevokeAllSessions(userId uint) error {
+	userKey := refreshUserKey(userId)
+	families, err := r.redis.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return fmt.Errorf("redis: %v", err)
+	}
+
+	for _, family := range families {
+		if err := r.RevokeFamily(family); err != nil {
+			return err
+		}
+	}
+
+	_, err = r.redis.Pipelined(ctx, func(p redis.Pipeliner) error {
+		p.Del(ctx, userKey)
+		p.Set(ctx, revokedBeforeKey(userId), time.Now().Unix(), r.accessTTL)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis: %v", err)
+	}
+
+	return nil
+}
+
+func (r *authRepo) IsAccessTokenRevoked(claims *helper.JWTCLAIMS) (bool, error) {
+	var denied *redis.IntCmd
+	var revokedBefore *redis.StringCmd
+	_, err := r.redis.Pipelined(ctx, func(p redis.Pipeliner) error {
+		denied = p.Exists(ctx, denylistKey(claims.ID))
+		revokedBefore = p.Get(ctx, revokedBeforeKey(claims.UserID))
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return false, fmt.Errorf("redis: %v", err)
+	}
+
+	if denied.Val() > 0 {
+		return true, nil
+	}
+
+	if before, err := revokedBefore.Int64(); err == nil && claims.IssuedAt != nil {
+		return claims.IssuedAt.Unix() < before, nil
+	}
+
+	return false, nil
+}
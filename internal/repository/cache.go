@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"api_shope/utils/helper"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// lock supaya hanya satu instance yang membangun ulang cache yang sama
+	rebuildLockPrefix = "lock:rebuild:"
+	rebuildLockTTL    = 10 * time.Second
+
+	// instance yang kalah lock menunggu hasil rebuild selama ini sebelum baca mysql sendiri
+	rebuildWait     = 2 * time.Second
+	rebuildWaitStep = 50 * time.Millisecond
+)
+
+// CacheOptions mengatur umur satu entry. setelah TTL entry dianggap basi tapi masih
+// disajikan selama Stale sambil dibangun ulang di background. Beta > 1 membuat
+// refresh dini lebih agresif (XFetch), 0 memakai nilai 1.
+type CacheOptions struct {
+	TTL   time.Duration
+	Stale time.Duration
+	Beta  float64
+}
+
+// cacheEntry disimpan di redis sebagai header 16 byte (FreshUntil dan Delta/lama rebuild,
+// keduanya unix ms) diikuti Data hasil codec, supaya tidak bergantung pada codec tertentu
+type cacheEntry struct {
+	Data       []byte
+	FreshUntil int64
+	Delta      int64
+}
+
+const entryHeaderSize = 16
+
+func (e cacheEntry) encode() []byte {
+	buf := make([]byte, entryHeaderSize+len(e.Data))
+	binary.BigEndian.PutUint64(buf[0:8], uint64(e.FreshUntil))
+	binary.BigEndian.PutUint64(buf[8:16], uint64(e.Delta))
+	copy(buf[entryHeaderSize:], e.Data)
+
+	return buf
+}
+
+func decodeEntry(raw []byte) (*cacheEntry, error) {
+	if len(raw) <= entryHeaderSize {
+		return nil, errors.New("entry terlalu pendek")
+	}
+
+	return &cacheEntry{
+		FreshUntil: int64(binary.BigEndian.Uint64(raw[0:8])),
+		Delta:      int64(binary.BigEndian.Uint64(raw[8:16])),
+		Data:       raw[entryHeaderSize:],
+	}, nil
+}
+
+// cacheAside adalah helper lazy loading yang tahan stampede:
+// request bersamaan di satu proses digabung (singleflight), antar instance
+// dijaga lock redis, dan entry diperbarui sebelum/sesudah kedaluwarsa tanpa
+// membuat request menunggu mysql.
+type cacheAside struct {
+	redis   *redis.Client
+	flight  flightGroup
+	metrics *familyMetrics
+}
+
+func newCacheAside(redis *redis.Client, metrics *familyMetrics) *cacheAside {
+	return &cacheAside{redis: redis, metrics: metrics}
+}
+
+// loader memuat data dari mysql dalam bentuk ter-encode beserta tag invalidasinya
+type loader func() ([]byte, []string, error)
+
+func (c *cacheAside) fetch(key string, opts CacheOptions, load loader) ([]byte, error) {
+	// entry rusak atau redis bermasalah diperlakukan sebagai miss, entry akan ditimpa saat rebuild
+	entry, err := c.get(key)
+	if err != nil {
+		c.metrics.fail()
+		log.Println(err)
+	}
+
+	if entry != nil {
+		c.metrics.hit()
+		now := time.Now()
+		if now.UnixMilli() >= entry.FreshUntil || shouldRefreshEarly(entry, opts.Beta, now) {
+			c.refreshAsync(key, opts, load)
+		}
+		return entry.Data, nil
+	}
+
+	c.metrics.miss()
+	return c.flight.do(key, func() ([]byte, error) {
+		return c.rebuild(key, opts, load, true)
+	})
+}
+
+func (c *cacheAside) get(key string) (*cacheEntry, error) {
+	raw, err := c.redis.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, &CacheError{Key: key, Op: "get", Err: err}
+	}
+
+	entry, err := decodeEntry(raw)
+	if err != nil {
+		return nil, &CacheError{Key: key, Op: "decode", Err: err}
+	}
+
+	return entry, nil
+}
+
+// shouldRefreshEarly adalah XFetch: makin dekat ke kedaluwarsa dan makin lama rebuild,
+// makin besar peluang satu request memperbarui entry lebih dulu
+func shouldRefreshEarly(entry *cacheEntry, beta float64, now time.Time) bool {
+	if beta <= 0 {
+		beta = 1
+	}
+
+	gap := float64(entry.Delta) * beta * -math.Log(1-rand.Float64())
+	return float64(now.UnixMilli())+gap >= float64(entry.FreshUntil)
+}
+
+func (c *cacheAside) refreshAsync(key string, opts CacheOptions, load loader) {
+	go func() {
+		// key flight terpisah supaya request yang miss tidak ikut menerima errRebuildBusy
+		_, err := c.flight.do(key+"#refresh", func() ([]byte, error) {
+			return c.rebuild(key, opts, load, false)
+		})
+		if err != nil && !errors.Is(err, errRebuildBusy) {
+			c.metrics.fail()
+			log.Printf("cache: gagal refresh %s: %v", key, err)
+		}
+	}()
+}
+
+var errRebuildBusy = errors.New("cache: rebuild sedang berjalan di instance lain")
+
+// releaseLockScript melepas lock rebuild / warmup hanya jika masih dipegang token ini
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// rebuild memuat data dari mysql di bawah lock redis. jika lock dipegang instance lain,
+// wait=true menunggu hasilnya muncul di redis, wait=false (refresh background) langsung mundur.
+func (c *cacheAside) rebuild(key string, opts CacheOptions, load loader, wait bool) ([]byte, error) {
+	lockKey := rebuildLockPrefix + key
+	token, err := helper.GenerateRandomToken(8)
+	if err != nil {
+		return nil, err
+	}
+
+	locked, err := c.redis.SetNX(ctx, lockKey, token, rebuildLockTTL).Result()
+	if err != nil {
+		// redis bermasalah, tetap layani dari mysql
+		c.metrics.fail()
+		log.Printf("cache: gagal mengambil lock %s: %v", key, err)
+		data, _, err := load()
+		return data, err
+	}
+
+	if !locked {
+		if !wait {
+			return nil, errRebuildBusy
+		}
+		if entry := c.waitForRebuild(key); entry != nil {
+			return entry.Data, nil
+		}
+		data, _, err := load()
+		return data, err
+	}
+	defer releaseLockScript.Run(ctx, c.redis, []string{lockKey}, token)
+
+	start := time.Now()
+	data, tags, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	delta := time.Since(start)
+	raw := cacheEntry{
+		Data:       data,
+		FreshUntil: time.Now().Add(opts.TTL).UnixMilli(),
+		Delta:      delta.Milliseconds(),
+	}.encode()
+
+	_, err = c.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, raw, opts.TTL+opts.Stale)
+		addTags(pipe, key, tags)
+		return nil
+	})
+	if err != nil {
+		c.metrics.fail()
+		return nil, fmt.Errorf("redis: %v", err)
+	}
+	c.metrics.rebuilt(delta)
+
+	return data, nil
+}
+
+func (c *cacheAside) waitForRebuild(key string) *cacheEntry {
+	deadline := time.Now().Add(rebuildWait)
+	for time.Now().Before(deadline) {
+		time.Sleep(rebuildWaitStep)
+
+		entry, err := c.get(key)
+		if err != nil {
+			return nil
+		}
+		if entry != nil {
+			return entry
+		}
+	}
+
+	return nil
+}
+
+// flightGroup menggabungkan pemanggilan bersamaan untuk key yang sama,
+// hanya pemanggil pertama yang menjalankan fn dan sisanya ikut menunggu hasilnya
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	wg  sync.WaitGroup
+	val []byte
+	err error
+}
+
+func (g *flightGroup) do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.val, call.err
+	}
+
+	call := &flightCall{}
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	call.val, call.err = fn()
+	call.wg.Done()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+
+	return call.val, call.err
+}
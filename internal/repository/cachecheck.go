@@ -0,0 +1,498 @@
+package repository
+
+import (
+	"api_shope/dto"
+	"api_shope/model"
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// CheckMode menentukan apa yang dilakukan pada key yang tidak sesuai dengan mysql
+type CheckMode int
+
+const (
+	// CheckOnly hanya melaporkan
+	CheckOnly CheckMode = iota
+	// CheckRepair menulis ulang key dari mysql, key yang tidak bisa diperbaiki dibuang
+	CheckRepair
+	// CheckEvict membuang key, nanti dibangun ulang saat dibaca
+	CheckEvict
+)
+
+const (
+	ActionRepaired = "repaired"
+	ActionEvicted  = "evicted"
+
+	legacyFamily = "legacy"
+	scanCount    = 100
+)
+
+// Drift adalah satu key cache yang isinya tidak sesuai dengan mysql
+type Drift struct {
+	Family string `json:"family"`
+	Key    string `json:"key"`
+	Reason string `json:"reason"`
+	Action string `json:"action,omitempty"`
+}
+
+// CheckResult adalah hasil pemeriksaan satu key family
+type CheckResult struct {
+	Family  string  `json:"family"`
+	Scanned int     `json:"scanned"`
+	Drift   []Drift `json:"drift"`
+}
+
+// CacheChecker membandingkan isi key family dengan baris mysql
+type CacheChecker struct {
+	repo *shopRepo
+	mode CheckMode
+}
+
+// checkFunc memeriksa satu key. reason kosong berarti sesuai, repair nil berarti
+// key hanya bisa dibuang
+type checkFunc func(key string, parts []string) (reason string, repair func() error, err error)
+
+type familyCheck struct {
+	family KeyFamily
+	check  checkFunc
+}
+
+func NewCacheChecker(db *gorm.DB, redis *redis.Client, mode CheckMode) *CacheChecker {
+	// L1 berukuran 0 tidak menyimpan apa pun, hanya dipakai untuk menyiarkan
+	// invalidasi ke instance api yang sedang berjalan
+	repo := NewShopRepo(db, redis, NewLocalCache(redis, 0, 0)).(*shopRepo)
+	return &CacheChecker{repo: repo, mode: mode}
+}
+
+// CheckFamilies adalah nama family yang bisa diperiksa
+func CheckFamilies() []string {
+	return []string{
+		productFamily.Name,
+		allProductFamily.Name,
+		allStoreFamily.Name,
+		myStoreFamily.Name,
+		cartItemFamily.Name,
+		cartIndexFamily.Name,
+		legacyFamily,
+	}
+}
+
+func (c *CacheChecker) checks() []familyCheck {
+	return []familyCheck{
+		{productFamily, c.checkProduct},
+		{allProductFamily, func(key string, parts []string) (string, func() error, error) {
+			return checkEntry(c.repo.allProduct, key, c.repo.loadAllProducts, allProductsTags)
+		}},
+		{allStoreFamily, func(key string, parts []string) (string, func() error, error) {
+			return checkEntry(c.repo.allStore, key, c.repo.loadAllStores, allStoresTags)
+		}},
+		{myStoreFamily, c.checkMyStore},
+		{cartItemFamily, c.checkCartItem},
+		{cartIndexFamily, c.checkCartIndex},
+	}
+}
+
+// Check memeriksa family yang diminta, tanpa argumen berarti semua family
+func (c *CacheChecker) Check(families ...string) ([]CheckResult, error) {
+	wanted := make(map[string]bool)
+	for _, name := range families {
+		wanted[name] = true
+	}
+
+	var results []CheckResult
+	for _, fc := range c.checks() {
+		if len(wanted) > 0 && !wanted[fc.family.Name] {
+			continue
+		}
+
+		result, err := c.checkFamily(fc)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+
+	if len(wanted) == 0 || wanted[legacyFamily] {
+		result, err := c.checkLegacy()
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func (c *CacheChecker) checkFamily(fc familyCheck) (CheckResult, error) {
+	result := CheckResult{Family: fc.family.Name}
+	current := fc.family.Key() + ":"
+
+	err := c.scan(fc.family.Name+":*", func(key string) error {
+		result.Scanned++
+
+		// versi lama atau format sebelum key family tidak dibaca lagi, cukup dibuang
+		if !strings.HasPrefix(key, current) {
+			return c.resolve(&result, key, "versi key lama", nil)
+		}
+
+		reason, repair, err := fc.check(key, strings.Split(strings.TrimPrefix(key, current), ":"))
+		if err != nil {
+			return err
+		}
+		if reason == "" {
+			return nil
+		}
+
+		return c.resolve(&result, key, reason, repair)
+	})
+
+	return result, err
+}
+
+// checkLegacy mencari key dari format lama yang tidak tercakup nama family mana pun
+func (c *CacheChecker) checkLegacy() (CheckResult, error) {
+	result := CheckResult{Family: legacyFamily}
+	for _, match := range []string{"store:all*", "user:*:cartitem*"} {
+		err := c.scan(match, func(key string) error {
+			result.Scanned++
+			return c.resolve(&result, key, "format key lama", nil)
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// Clear membuang semua key family, key format lama dan set tag tanpa membandingkan
+// dengan mysql, dipakai setelah isi database diganti total (misalnya reset seed)
+func (c *CacheChecker) Clear() (int, error) {
+	var patterns []string
+	for _, fc := range c.checks() {
+		patterns = append(patterns, fc.family.Name+":*")
+	}
+	patterns = append(patterns, "store:all*", "user:*:cartitem*", tagPrefix+"*")
+
+	cleared := 0
+	for _, match := range patterns {
+		var keys []string
+		flush := func() error {
+			if len(keys) == 0 {
+				return nil
+			}
+			if err := c.repo.redis.Del(ctx, keys...).Err(); err != nil {
+				return fmt.Errorf("redis: %v", err)
+			}
+			recordEvictions(keys)
+			if err := c.repo.l1.Invalidate(keys...); err != nil {
+				return err
+			}
+			cleared += len(keys)
+			keys = keys[:0]
+			return nil
+		}
+
+		err := c.scan(match, func(key string) error {
+			keys = append(keys, key)
+			if len(keys) < scanCount {
+				return nil
+			}
+			return flush()
+		})
+		if err == nil {
+			err = flush()
+		}
+		if err != nil {
+			return cleared, err
+		}
+	}
+
+	return cleared, nil
+}
+
+func (c *CacheChecker) scan(match string, fn func(key string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := c.repo.redis.Scan(ctx, cursor, match, scanCount).Result()
+		if err != nil {
+			return fmt.Errorf("redis: %v", err)
+		}
+
+		for _, key := range keys {
+			if err := fn(key); err != nil {
+				return err
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+func (c *CacheChecker) resolve(result *CheckResult, key, reason string, repair func() error) error {
+	drift := Drift{Family: result.Family, Key: key, Reason: reason}
+
+	switch {
+	case c.mode == CheckOnly:
+	case c.mode == CheckRepair && repair != nil:
+		if err := repair(); err != nil {
+			return err
+		}
+		drift.Action = ActionRepaired
+	default:
+		if err := c.repo.redis.Del(ctx, key).Err(); err != nil {
+			return fmt.Errorf("redis: %v", err)
+		}
+		recordEvictions([]string{key})
+		drift.Action = ActionEvicted
+	}
+
+	if drift.Action != "" {
+		if err := c.repo.l1.Invalidate(key); err != nil {
+			return err
+		}
+	}
+
+	result.Drift = append(result.Drift, drift)
+	return nil
+}
+
+func parseIDs(parts []string, n int) ([]uint, bool) {
+	if len(parts) != n {
+		return nil, false
+	}
+
+	ids := make([]uint, n)
+	for i, part := range parts {
+		id, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		ids[i] = uint(id)
+	}
+
+	return ids, true
+}
+
+// cacheReason mengubah error baca cache menjadi alasan drift. ErrCacheMiss berarti
+// key kedaluwarsa selama scan dan tidak perlu dilaporkan.
+func cacheReason(err error) (reason string, skip bool, fatal error) {
+	var cacheErr *CacheError
+	switch {
+	case err == nil:
+		return "", false, nil
+	case errors.Is(err, ErrCacheMiss):
+		return "", true, nil
+	case errors.As(err, &cacheErr) && cacheErr.Op != "hgetall" && cacheErr.Op != "get":
+		return "entry rusak: " + cacheErr.Err.Error(), false, nil
+	default:
+		return "", false, err
+	}
+}
+
+// diffFields mengembalikan nama field (tag redis) yang berbeda antara dua entry hash
+func diffFields(cached, fresh interface{}) []string {
+	a, b := reflect.ValueOf(cached), reflect.ValueOf(fresh)
+
+	var fields []string
+	for i := 0; i < a.NumField(); i++ {
+		name, _, _ := strings.Cut(a.Type().Field(i).Tag.Get("redis"), ",")
+		x, y := a.Field(i).Interface(), b.Field(i).Interface()
+
+		if t, ok := x.(time.Time); ok {
+			if !t.Equal(y.(time.Time)) {
+				fields = append(fields, name)
+			}
+			continue
+		}
+		if x != y {
+			fields = append(fields, name)
+		}
+	}
+
+	return fields
+}
+
+func (c *CacheChecker) checkProduct(key string, parts []string) (string, func() error, error) {
+	ids, ok := parseIDs(parts, 1)
+	if !ok {
+		return "key tidak dikenal", nil, nil
+	}
+
+	cached, err := c.repo.products.Get(key)
+	reason, skip, err := cacheReason(err)
+	if skip || err != nil {
+		return "", nil, err
+	}
+
+	var product model.Product
+	if err := c.repo.db.First(&product, ids[0]).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "product tidak ada di mysql", nil, nil
+		}
+		return "", nil, err
+	}
+
+	fresh := newProductEntry(product)
+	if reason == "" {
+		diff := diffFields(*cached, fresh)
+		if len(diff) == 0 {
+			return "", nil, nil
+		}
+		reason = "berbeda dengan mysql: " + strings.Join(diff, ", ")
+	}
+
+	return reason, func() error {
+		return c.repo.products.Set(key, fresh, productEntryTags(fresh)...)
+	}, nil
+}
+
+func (c *CacheChecker) checkMyStore(key string, parts []string) (string, func() error, error) {
+	ids, ok := parseIDs(parts, 1)
+	if !ok {
+		return "key tidak dikenal", nil, nil
+	}
+
+	return checkEntry(c.repo.myStore, key, func() (dto.StoreAndProduct, error) {
+		return c.repo.loadMyStore(ids[0])
+	}, myStoreTags)
+}
+
+// checkEntry membandingkan entry Cache dengan hasil load yang di-encode dengan codec yang sama
+func checkEntry[T any](cache *Cache[T], key string, load func() (T, error), tagsOf func(T) []string) (string, func() error, error) {
+	entry, err := cache.aside.get(key)
+	if entry == nil && err == nil {
+		return "", nil, nil
+	}
+	reason, _, err := cacheReason(err)
+	if err != nil {
+		return "", nil, err
+	}
+
+	value, err := load()
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "data tidak ada di mysql", nil, nil
+		}
+		return "", nil, err
+	}
+
+	if reason == "" {
+		data, err := cache.codec.Marshal(value)
+		if err != nil {
+			return "", nil, &CacheError{Key: key, Op: cache.codec.Name() + " encode", Err: err}
+		}
+		if bytes.Equal(data, entry.Data) {
+			return "", nil, nil
+		}
+		reason = "berbeda dengan mysql"
+	}
+
+	return reason, func() error {
+		return cache.Set(key, value, tagsOf(value)...)
+	}, nil
+}
+
+func cartItemFromModel(item model.CartItem) dto.CartItem {
+	cartItem := dto.CartItem{
+		ID:               item.ID,
+		UserID:           item.UserID,
+		PurchaseAmount:   item.PurchaseAmount,
+		IsPaid:           item.IsPaid,
+		IsProductDeleted: item.IsProductDeleted,
+		CreatedAt:        item.CreatedAt,
+	}
+	if item.ProductID != nil {
+		cartItem.ProductID = *item.ProductID
+	}
+
+	return cartItem
+}
+
+func (c *CacheChecker) checkCartItem(key string, parts []string) (string, func() error, error) {
+	ids, ok := parseIDs(parts, 2)
+	if !ok {
+		return "key tidak dikenal", nil, nil
+	}
+
+	cached, err := c.repo.cartItems.Get(key)
+	reason, skip, err := cacheReason(err)
+	if skip || err != nil {
+		return "", nil, err
+	}
+
+	var item model.CartItem
+	if err := c.repo.db.Where("id = ? AND user_id = ?", ids[1], ids[0]).First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "cart item tidak ada di mysql", nil, nil
+		}
+		return "", nil, err
+	}
+
+	fresh := newCartItemEntry(cartItemFromModel(item))
+	if reason == "" {
+		diff := diffFields(*cached, fresh)
+		if len(diff) == 0 {
+			return "", nil, nil
+		}
+		reason = "berbeda dengan mysql: " + strings.Join(diff, ", ")
+	}
+
+	return reason, func() error {
+		return c.repo.cartItems.Set(key, fresh, cartItemTags(fresh)...)
+	}, nil
+}
+
+func (c *CacheChecker) checkCartIndex(key string, parts []string) (string, func() error, error) {
+	ids, ok := parseIDs(parts, 1)
+	if !ok {
+		return "key tidak dikenal", nil, nil
+	}
+	userId := ids[0]
+
+	members, err := c.repo.redis.SMembers(ctx, key).Result()
+	if err != nil {
+		return "", nil, fmt.Errorf("redis: %v", err)
+	}
+	if len(members) == 0 {
+		return "", nil, nil
+	}
+
+	var items []model.CartItem
+	if err := c.repo.db.Where("user_id = ?", userId).Find(&items).Error; err != nil {
+		return "", nil, err
+	}
+	if len(items) == 0 {
+		return "user tidak punya cart item di mysql", nil, nil
+	}
+
+	want := make([]string, len(items))
+	for i, item := range items {
+		want[i] = strconv.FormatUint(uint64(item.ID), 10)
+	}
+	slices.Sort(members)
+	slices.Sort(want)
+	if slices.Equal(members, want) {
+		return "", nil, nil
+	}
+
+	return fmt.Sprintf("index berisi %d item, mysql %d item", len(members), len(items)), func() error {
+		cartItems := make([]dto.CartItem, len(items))
+		for i, item := range items {
+			cartItems[i] = cartItemFromModel(item)
+		}
+		return c.repo.cacheCartItems(userId, cartItems)
+	}, nil
+}
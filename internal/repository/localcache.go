@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// semua instance api subscribe ke channel ini, isinya daftar key dipisah spasi
+const invalidateChannel = "cache:invalidate"
+
+// LocalCache adalah cache L1 (LRU di memori proses) di depan redis untuk data yang
+// sangat sering dibaca. TTL dibuat pendek karena stock juga berubah lewat pembelian,
+// sedangkan perubahan product disebar ke instance lain lewat redis pub/sub.
+// nil *LocalCache berarti L1 dimatikan, semua method aman dipanggil.
+type LocalCache struct {
+	redis *redis.Client
+	size  int
+	ttl   time.Duration
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type localItem struct {
+	key       string
+	value     interface{}
+	expiresAt time.Time
+}
+
+func NewLocalCache(redis *redis.Client, size int, ttl time.Duration) *LocalCache {
+	return &LocalCache{
+		redis: redis,
+		size:  size,
+		ttl:   ttl,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (c *LocalCache) get(key string) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	item := el.Value.(*localItem)
+	if time.Now().After(item.expiresAt) {
+		c.removeElement(el)
+		return nil, false
+	}
+
+	c.ll.MoveToFront(el)
+	return item.value, true
+}
+
+func (c *LocalCache) set(key string, value interface{}) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		item := el.Value.(*localItem)
+		item.value = value
+		item.expiresAt = time.Now().Add(c.ttl)
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&localItem{key: key, value: value, expiresAt: time.Now().Add(c.ttl)})
+	for c.ll.Len() > c.size {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+func (c *LocalCache) removeElement(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*localItem).key)
+}
+
+func (c *LocalCache) remove(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if el, ok := c.items[key]; ok {
+			c.removeElement(el)
+		}
+	}
+}
+
+func (c *LocalCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+// Invalidate menghapus key di instance ini lalu memberi tahu instance lain
+func (c *LocalCache) Invalidate(keys ...string) error {
+	if c == nil || len(keys) == 0 {
+		return nil
+	}
+
+	c.remove(keys...)
+	if err := c.redis.Publish(ctx, invalidateChannel, strings.Join(keys, " ")).Err(); err != nil {
+		return &CacheError{Key: strings.Join(keys, ","), Op: "publish", Err: err}
+	}
+
+	return nil
+}
+
+// Listen menerima pesan invalidasi sampai listenCtx selesai. setiap kali (re)subscribe
+// seluruh L1 dikosongkan, karena pesan selama koneksi putus tidak akan diterima.
+func (c *LocalCache) Listen(listenCtx context.Context) {
+	if c == nil {
+		return
+	}
+
+	pubsub := c.redis.Subscribe(listenCtx, invalidateChannel)
+	defer pubsub.Close()
+
+	for {
+		msg, err := pubsub.Receive(listenCtx)
+		if err != nil {
+			if listenCtx.Err() != nil || errors.Is(err, redis.ErrClosed) {
+				return
+			}
+			log.Println("cache: gagal menerima invalidasi:", err)
+			c.purge()
+			time.Sleep(time.Second)
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			c.purge()
+		case *redis.Message:
+			c.remove(strings.Fields(m.Payload)...)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// familyMetrics adalah counter cache satu key family di proses ini.
+// hit dari L1 dihitung terpisah supaya hit ratio redis tetap terlihat.
+type familyMetrics struct {
+	ttl   time.Duration
+	stale time.Duration
+
+	hits      atomic.Int64
+	l1Hits    atomic.Int64
+	misses    atomic.Int64
+	errors    atomic.Int64
+	evictions atomic.Int64
+
+	// rebuild = memuat ulang dari mysql lalu menulis ke cache
+	rebuilds     atomic.Int64
+	rebuildNanos atomic.Int64
+	rebuildMax   atomic.Int64
+}
+
+// CacheMetrics adalah snapshot counter satu key family sejak proses berjalan
+type CacheMetrics struct {
+	Family       string  `json:"family"`
+	TTL          string  `json:"ttl"`
+	Stale        string  `json:"stale,omitempty"`
+	Hits         int64   `json:"hits"`
+	L1Hits       int64   `json:"l1_hits"`
+	Misses       int64   `json:"misses"`
+	Errors       int64   `json:"errors"`
+	Evictions    int64   `json:"evictions"`
+	HitRatio     float64 `json:"hit_ratio"`
+	Rebuilds     int64   `json:"rebuilds"`
+	RebuildAvgMs float64 `json:"rebuild_avg_ms"`
+	RebuildMaxMs float64 `json:"rebuild_max_ms"`
+}
+
+var cacheMetrics = struct {
+	mu       sync.Mutex
+	families map[string]*familyMetrics
+}{families: make(map[string]*familyMetrics)}
+
+// metricsFor mengembalikan counter family, dibuat saat pertama kali dipakai
+func metricsFor(family KeyFamily) *familyMetrics {
+	cacheMetrics.mu.Lock()
+	defer cacheMetrics.mu.Unlock()
+
+	m, ok := cacheMetrics.families[family.Name]
+	if !ok {
+		m = &familyMetrics{ttl: family.TTL, stale: family.Stale}
+		cacheMetrics.families[family.Name] = m
+	}
+
+	return m
+}
+
+func (m *familyMetrics) hit()   { m.hits.Add(1) }
+func (m *familyMetrics) l1Hit() { m.l1Hits.Add(1) }
+func (m *familyMetrics) miss()  { m.misses.Add(1) }
+func (m *familyMetrics) fail()  { m.errors.Add(1) }
+
+func (m *familyMetrics) rebuilt(d time.Duration) {
+	m.rebuilds.Add(1)
+	m.rebuildNanos.Add(int64(d))
+	for {
+		cur := m.rebuildMax.Load()
+		if int64(d) <= cur || m.rebuildMax.CompareAndSwap(cur, int64(d)) {
+			return
+		}
+	}
+}
+
+// recordEvictions menghitung key yang dibuang per family dari prefix key-nya
+func recordEvictions(keys []string) {
+	cacheMetrics.mu.Lock()
+	defer cacheMetrics.mu.Unlock()
+
+	for _, key := range keys {
+		name, _, _ := strings.Cut(key, ":")
+		if m, ok := cacheMetrics.families[name]; ok {
+			m.evictions.Add(1)
+		}
+	}
+}
+
+// CacheStats mengembalikan counter semua family, urut nama
+func CacheStats() []CacheMetrics {
+	cacheMetrics.mu.Lock()
+	defer cacheMetrics.mu.Unlock()
+
+	stats := make([]CacheMetrics, 0, len(cacheMetrics.families))
+	for name, m := range cacheMetrics.families {
+		s := CacheMetrics{
+			Family:    name,
+			TTL:       m.ttl.String(),
+			Hits:      m.hits.Load(),
+			L1Hits:    m.l1Hits.Load(),
+			Misses:    m.misses.Load(),
+			Errors:    m.errors.Load(),
+			Evictions: m.evictions.Load(),
+			Rebuilds:  m.rebuilds.Load(),
+		}
+		if m.stale > 0 {
+			s.Stale = m.stale.String()
+		}
+
+		if lookups := s.Hits + s.L1Hits + s.Misses; lookups > 0 {
+			s.HitRatio = float64(s.Hits+s.L1Hits) / float64(lookups)
+		}
+		if s.Rebuilds > 0 {
+			s.RebuildAvgMs = float64(m.rebuildNanos.Load()) / float64(s.Rebuilds) / float64(time.Millisecond)
+		}
+		s.RebuildMaxMs = float64(m.rebuildMax.Load()) / float64(time.Millisecond)
+
+		stats = append(stats, s)
+	}
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Family < stats[j].Family })
+	return stats
+}
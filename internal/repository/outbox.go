@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"api_shope/model"
+	"api_shope/utils/helper"
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// addOutbox menulis job ke tabel outbox memakai tx yang sedang berjalan,
+// job baru masuk queue setelah transaksi commit dan dibaca relay worker
+func addOutbox(tx *gorm.DB, op string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("outbox: %v", err)
+	}
+
+	jobID, err := helper.GenerateRandomToken(12)
+	if err != nil {
+		return fmt.Errorf("outbox: %v", err)
+	}
+
+	return tx.Create(&model.Outbox{
+		JobID:   jobID,
+		Op:      op,
+		Payload: string(data),
+	}).Error
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"api_shope/internal/queue"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type QueueRepo interface {
+	//dead letter
+	ListDeadJobs() ([]queue.DeadJob, error)
+	ReplayDeadJob(id string) error
+
+	//inspeksi
+	GetQueueStats() ([]queue.QueueStats, error)
+	GetJob(id string) (*queue.JobStatus, error)
+	RetryJob(id string) error
+	DiscardJob(id string) error
+}
+
+type queueRepo struct {
+	redis *redis.Client
+}
+
+func NewQueueRepo(redis *redis.Client) QueueRepo {
+	return &queueRepo{redis}
+}
+
+func (r *queueRepo) ListDeadJobs() ([]queue.DeadJob, error) {
+	return queue.ListDead(ctx, r.redis)
+}
+
+func (r *queueRepo) ReplayDeadJob(id string) error {
+	return queue.ReplayDead(ctx, r.redis, id)
+}
+
+func (r *queueRepo) GetQueueStats() ([]queue.QueueStats, error) {
+	return queue.Stats(ctx, r.redis)
+}
+
+func (r *queueRepo) GetJob(id string) (*queue.JobStatus, error) {
+	return queue.GetStatus(ctx, r.redis, id)
+}
+
+func (r *queueRepo) RetryJob(id string) error {
+	return queue.Retry(ctx, r.redis, id)
+}
+
+func (r *queueRepo) DiscardJob(id string) error {
+	return queue.Discard(ctx, r.redis, id)
+}
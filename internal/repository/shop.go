@@ -2,21 +2,22 @@ package repository
 
 import (
 	"api_shope/dto"
+	"api_shope/internal/queue"
 	"api_shope/model"
 	"api_shope/utils/helper"
 	"context"
-	"encoding/json"
-	"fmt"
+	"errors"
 	"log"
-	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ShopRepo interface {
 	IsUserAdminStore(userId, storeId uint) (bool, error)
+	IsUserVerified(userId uint) (bool, error)
 
 	//store
 	GetMyStore(userId uint) (*dto.StoreAndProduct, error)
@@ -39,15 +40,129 @@ type ShopRepo interface {
 	UpdatePaidCartItem(req *dto.UpdatePaidCartItemReq) error
 	DeleteCartItem(userId, id uint) error
 	CheckStock(id uint, req int) (bool, error)
+	GetMyCartTotal(userId uint) (*dto.CartTotal, error)
+
+	//order
+	Checkout(req *dto.CheckoutReq) (*dto.Order, error)
+	GetMyOrders(userId uint) ([]dto.Order, error)
+	GetMyOrder(userId, id uint) (*dto.Order, error)
+
+	//cache
+	WarmCache(opts WarmupOptions) error
+	Warmup(opts WarmupOptions) (*WarmupResult, error)
+	InvalidateTags(tags ...string) error
+	CacheMetrics() []CacheMetrics
 }
 
 type shopRepo struct {
 	db    *gorm.DB
 	redis *redis.Client
+
+	products   *HashCache[productEntry]
+	cartItems  *HashCache[cartItemEntry]
+	allProduct *Cache[[]dto.Product]
+	allStore   *Cache[[]dto.JustStore]
+	myStore    *Cache[dto.StoreAndProduct]
+
+	// index cart berupa set biasa, bukan Cache/HashCache, jadi counternya dipegang di sini
+	cartIndex *familyMetrics
+
+	// L1 opsional di depan redis untuk product, nil jika dimatikan
+	l1 *LocalCache
+}
+
+func NewShopRepo(db *gorm.DB, redis *redis.Client, l1 *LocalCache) ShopRepo {
+	return &shopRepo{
+		db:    db,
+		redis: redis,
+		l1:    l1,
+
+		products:   NewHashCache[productEntry](redis, productFamily),
+		cartItems:  NewHashCache[cartItemEntry](redis, cartItemFamily),
+		allProduct: NewCache[[]dto.Product](redis, allProductFamily, MsgpackCodec),
+		allStore:   NewCache[[]dto.JustStore](redis, allStoreFamily, JSONCodec),
+		myStore:    NewCache[dto.StoreAndProduct](redis, myStoreFamily, JSONCodec),
+		cartIndex:  metricsFor(cartIndexFamily),
+	}
+}
+
+// umur cache per key family, selama Stale entry basi masih disajikan sambil dibangun ulang
+var (
+	productFamily    = KeyFamily{Name: "product", Version: 1, CacheOptions: CacheOptions{TTL: 30 * time.Minute}}
+	allProductFamily = KeyFamily{Name: "products", Version: 2, CacheOptions: CacheOptions{TTL: 30 * time.Minute, Stale: 5 * time.Minute}}
+	allStoreFamily   = KeyFamily{Name: "stores", Version: 1, CacheOptions: CacheOptions{TTL: 15 * time.Minute, Stale: 5 * time.Minute}}
+	myStoreFamily    = KeyFamily{Name: "mystore", Version: 1, CacheOptions: CacheOptions{TTL: 20 * time.Minute, Stale: 5 * time.Minute}}
+	cartItemFamily   = KeyFamily{Name: "cartitem", Version: 2, CacheOptions: CacheOptions{TTL: 30 * time.Minute}}
+	cartIndexFamily  = KeyFamily{Name: "cartitems", Version: 1, CacheOptions: CacheOptions{TTL: 30 * time.Minute}}
+)
+
+// productEntry adalah isi hash product, field stock diubah langsung oleh script reservasi
+type productEntry struct {
+	ID        uint      `redis:"id"`
+	StoreID   uint      `redis:"store_id"`
+	Name      string    `redis:"name"`
+	Stock     int       `redis:"stock"`
+	Price     int64     `redis:"price"`
+	Currency  string    `redis:"currency"`
+	CreatedAt time.Time `redis:"created_at"`
+}
+
+func newProductEntry(p model.Product) productEntry {
+	return productEntry{
+		ID:        p.ID,
+		StoreID:   p.StoreID,
+		Name:      p.Name,
+		Stock:     p.Stock,
+		Price:     p.Price,
+		Currency:  p.Currency,
+		CreatedAt: p.CreatedAt,
+	}
+}
+
+func (e productEntry) toDTO() dto.Product {
+	return dto.Product{
+		ID:        e.ID,
+		StoreID:   e.StoreID,
+		Name:      e.Name,
+		Stock:     e.Stock,
+		Price:     e.Price,
+		Currency:  e.Currency,
+		CreatedAt: e.CreatedAt,
+	}
+}
+
+func productEntryTags(e productEntry) []string {
+	return []string{productTag(e.ID), storeTag(e.StoreID)}
+}
+
+type cartItemEntry struct {
+	ID               uint      `redis:"id"`
+	UserID           uint      `redis:"user_id"`
+	ProductID        uint      `redis:"product_id"`
+	PurchaseAmount   int       `redis:"purchase_amount"`
+	IsPaid           bool      `redis:"is_paid"`
+	IsProductDeleted bool      `redis:"is_product_deleted"`
+	CreatedAt        time.Time `redis:"created_at"`
+}
+
+func newCartItemEntry(c dto.CartItem) cartItemEntry {
+	return cartItemEntry{
+		ID:               c.ID,
+		UserID:           c.UserID,
+		ProductID:        c.ProductID,
+		PurchaseAmount:   c.PurchaseAmount,
+		IsPaid:           c.IsPaid,
+		IsProductDeleted: c.IsProductDeleted,
+		CreatedAt:        c.CreatedAt,
+	}
 }
 
-func NewShopRepo(db *gorm.DB, redis *redis.Client) ShopRepo {
-	return &shopRepo{db, redis}
+func cartItemTags(e cartItemEntry) []string {
+	tags := []string{userTag(e.UserID)}
+	if e.ProductID != 0 {
+		tags = append(tags, productTag(e.ProductID))
+	}
+	return tags
 }
 
 var ctx = context.Background()
@@ -61,24 +176,33 @@ func (r *shopRepo) IsUserAdminStore(userId, storeId uint) (bool, error) {
 	return count > 0, nil
 }
 
+func (r *shopRepo) IsUserVerified(userId uint) (bool, error) {
+	var verified bool
+	if err := r.db.Model(&model.User{}).Select("verified").Where("id = ?", userId).Scan(&verified).Error; err != nil {
+		return false, err
+	}
+
+	return verified, nil
+}
+
 // penerapan metode caching dengan lazy loading
 func (r *shopRepo) GetMyStore(userId uint) (*dto.StoreAndProduct, error) {
-	key := fmt.Sprintf("mystore:user:%d", userId)
-
-	cachedData, err := r.redis.Get(ctx, key).Result()
-	if err == nil && cachedData != "" {
-		var cachedStore dto.StoreAndProduct
-		if err := json.Unmarshal([]byte(cachedData), &cachedStore); err == nil {
-			log.Println("data dari redis")
-			return &cachedStore, nil
-		}
+	key := r.myStore.Key(userId)
+
+	response, err := r.myStore.Fetch(key, func() (dto.StoreAndProduct, error) {
+		return r.loadMyStore(userId)
+	}, myStoreTags)
+	if err != nil {
+		return nil, err
 	}
 
-	log.Println("data dari mysql")
+	return &response, nil
+}
 
+func (r *shopRepo) loadMyStore(userId uint) (dto.StoreAndProduct, error) {
 	var store model.Store
 	if err := r.db.Preload("Product").Where("admin_id = ?", userId).First(&store).Error; err != nil {
-		return nil, err
+		return dto.StoreAndProduct{}, err
 	}
 
 	var getProduct []dto.Product
@@ -88,54 +212,47 @@ func (r *shopRepo) GetMyStore(userId uint) (*dto.StoreAndProduct, error) {
 			ID:        p.ID,
 			Name:      p.Name,
 			Stock:     p.Stock,
+			Price:     p.Price,
+			Currency:  p.Currency,
 			CreatedAt: p.CreatedAt,
 		})
 	}
 
-	response := dto.StoreAndProduct{
+	return dto.StoreAndProduct{
 		ID:        store.ID,
 		AdminID:   store.AdminID,
 		Name:      store.Name,
 		CreatedAt: store.CreatedAt,
 		Product:   getProduct,
-	}
+	}, nil
+}
 
-	jsonData, _ := json.Marshal(response)
-	err = r.redis.Set(ctx, key, jsonData, 20*time.Minute).Err()
-	if err != nil {
-		return nil, fmt.Errorf("redis: %v", err)
+// mystore ikut dibuang saat store, admin, atau salah satu product di dalamnya berubah
+func myStoreTags(store dto.StoreAndProduct) []string {
+	tags := []string{userTag(store.AdminID), storeTag(store.ID)}
+	for _, p := range store.Product {
+		tags = append(tags, productTag(p.ID))
 	}
-
-	return &response, nil
+	return tags
 }
 
 func (r *shopRepo) GetAllStore() ([]dto.JustStore, error) {
-	key := fmt.Sprintln("store:all")
-
-	cachedData, err := r.redis.Get(ctx, key).Result()
-	if err == nil && cachedData != "" {
-		var cachedShops []dto.JustStore
-		if err := json.Unmarshal([]byte(cachedData), &cachedShops); err == nil {
-			log.Println("data dari redis")
-			return cachedShops, nil
-		}
-	}
+	return r.allStore.Fetch(r.allStore.Key("all"), r.loadAllStores, allStoresTags)
+}
 
-	log.Println("data dari mysql")
+func (r *shopRepo) loadAllStores() ([]dto.JustStore, error) {
 	var shops []dto.JustStore
 	if err := r.db.Model(&model.Store{}).Select("id", "name", "admin_id", "created_at").Find(&shops).Error; err != nil {
-
 		return nil, err
 	}
 
-	jsonData, _ := json.Marshal(shops)
-	if err := r.redis.Set(ctx, key, jsonData, 15*time.Minute).Err(); err != nil {
-		return nil, fmt.Errorf("redis: %v", err)
-	}
-
 	return shops, nil
 }
 
+func allStoresTags([]dto.JustStore) []string {
+	return []string{allStoresTag}
+}
+
 func (r *shopRepo) CreateStore(req *dto.CreateStoreReq) error {
 	newStore := model.Store{
 		Name:    req.Name,
@@ -146,6 +263,7 @@ func (r *shopRepo) CreateStore(req *dto.CreateStoreReq) error {
 		return err
 	}
 
+	r.invalidate(allStoresTag, userTag(req.AdminID))
 	return nil
 }
 
@@ -156,6 +274,7 @@ func (r *shopRepo) UpdateStore(req *dto.UpdateStoreReq) error {
 		return err
 	}
 
+	r.invalidate(allStoresTag, storeTag(req.ID))
 	return nil
 }
 
@@ -164,138 +283,127 @@ func (r *shopRepo) DeleteStore(id uint) error {
 		return err
 	}
 
+	// product di store ikut terhapus (cascade), jadi list product juga dibuang
+	r.invalidate(allStoresTag, storeTag(id), allProductsTag)
 	return nil
 }
 
 // penerapan write-around caching (penggunaan lazy loading dan write trough yg bersamaan)
 func (r *shopRepo) CreateProduct(req *dto.CreateProductReq) error {
 	newProduct := model.Product{
-		Name:    req.Name,
-		StoreID: req.StoreID,
-		Stock:   req.Stock,
+		Name:     req.Name,
+		StoreID:  req.StoreID,
+		Stock:    req.Stock,
+		Price:    req.Price,
+		Currency: req.Currency,
 	}
 
 	if err := r.db.Create(&newProduct).Error; err != nil {
 		return err
 	}
 
-	key := fmt.Sprintf("product:%d", newProduct.ID)
+	r.invalidate(allProductsTag, storeTag(newProduct.StoreID))
 
-	_, err := r.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
-		pipe.HSet(ctx, key, map[string]interface{}{
-			"name":       newProduct.Name,
-			"store_id":   newProduct.StoreID,
-			"stock":      newProduct.Stock,
-			"created_at": newProduct.CreatedAt,
-		})
-		pipe.Expire(ctx, key, 30*time.Minute)
-		pipe.Del(ctx, "products:all")
-		return nil
-	})
-	if err != nil {
-		return fmt.Errorf("redis: %v", err)
+	entry := newProductEntry(newProduct)
+	if err := r.products.Set(r.products.Key(newProduct.ID), entry, productEntryTags(entry)...); err != nil {
+		// product sudah tersimpan, hash dibangun ulang saat pertama dibaca
+		log.Println(err)
 	}
 
 	return nil
 }
 
 func (r *shopRepo) UpdateProduct(req *dto.UpdateProductReq) error {
-	if err := r.db.Model(&model.Product{}).Where("id = ?", req.ID).Updates(map[string]interface{}{
+	updates := map[string]interface{}{
 		"name":  req.Name,
 		"stock": req.Stock,
-	}).Error; err != nil {
-		return err
 	}
-	key := fmt.Sprintf("product:%d", req.ID)
+	if req.Price != nil {
+		updates["price"] = *req.Price
+	}
+	if req.Currency != nil {
+		updates["currency"] = *req.Currency
+	}
 
-	_, err := r.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
-		pipe.HSet(ctx, key,
-			"name", req.Name,
-			"stock", req.Stock,
-		)
-		pipe.Del(ctx, "products:all")
-		return nil
-	})
-	if err != nil {
-		return fmt.Errorf("redis: %v", err)
+	if err := r.db.Model(&model.Product{}).Where("id = ?", req.ID).Updates(updates).Error; err != nil {
+		return err
 	}
 
+	r.invalidate(productTag(req.ID), allProductsTag)
 	return nil
 }
 
 func (r *shopRepo) DeleteProduct(id uint) error {
-	tx := r.db.Begin()
-	if err := tx.Model(&model.CartItem{}).Where("product_id = ?", id).Update("is_product_deleted", true).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
-	if err := tx.Delete(&model.Product{}, id).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	tx.Commit()
-
-	key := fmt.Sprintf("product:%d", id)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.CartItem{}).Where("product_id = ?", id).Update("is_product_deleted", true).Error; err != nil {
+			return err
+		}
 
-	_, err := r.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
-		pipe.HDel(ctx, key)
-		pipe.Del(ctx, "products:all")
-		return nil
+		return tx.Delete(&model.Product{}, id).Error
 	})
 	if err != nil {
-		return fmt.Errorf("redis: %v", err)
+		return err
 	}
 
+	// cart item yang memuat product ini juga ditag product:id
+	r.invalidate(productTag(id), allProductsTag)
 	return nil
 }
 
 func (r *shopRepo) GetProduct(id uint) (*dto.Product, error) {
-	key := fmt.Sprintf("product:%d", id)
+	key := r.products.Key(id)
+	if cached, ok := r.l1.get(key); ok {
+		r.products.metrics.l1Hit()
+		product := cached.(dto.Product)
+		return &product, nil
+	}
 
-	var product model.Product
-	data, err := r.redis.HGetAll(ctx, key).Result()
+	cached, err := r.products.Get(key)
 	if err == nil {
-		stock, _ := strconv.Atoi(data["stock"])
-		storeID, _ := strconv.Atoi(data["store_id"])
-		createdAt, _ := time.Parse(time.RFC3339, data["created_at"])
-
-		product := dto.Product{
-			ID:        id,
-			Name:      data["name"],
-			Stock:     stock,
-			StoreID:   uint(storeID),
-			CreatedAt: createdAt,
-		}
-
-		fmt.Println("data dari redis")
+		product := cached.toDTO()
+		r.l1.set(key, product)
 		return &product, nil
 	}
+	if !errors.Is(err, ErrCacheMiss) {
+		log.Println(err)
+	}
 
+	start := time.Now()
+	var product model.Product
 	if err := r.db.First(&product, id).Error; err != nil {
 		return nil, err
 	}
 
-	fmt.Println("data dari mysql")
-	return &dto.Product{
-		ID:        product.ID,
-		StoreID:   product.StoreID,
-		Name:      product.Name,
-		Stock:     product.Stock,
-		CreatedAt: product.CreatedAt,
-	}, nil
+	entry := newProductEntry(product)
+	if err := r.products.Set(key, entry, productEntryTags(entry)...); err != nil {
+		log.Println(err)
+	} else {
+		r.products.metrics.rebuilt(time.Since(start))
+	}
+
+	response := entry.toDTO()
+	r.l1.set(key, response)
+	return &response, nil
 }
 
 func (r *shopRepo) GetAllProduct() ([]dto.Product, error) {
-	cached, err := r.redis.Get(ctx, "products:all").Result()
-	if err == nil {
-		var products []dto.Product
-		if json.Unmarshal([]byte(cached), &products) == nil {
-			fmt.Println("data dari redis")
-			return products, nil
-		}
+	key := r.allProduct.Key("all")
+	if cached, ok := r.l1.get(key); ok {
+		// salin supaya pemanggil tidak mengubah isi L1
+		r.allProduct.metrics.l1Hit()
+		return append([]dto.Product(nil), cached.([]dto.Product)...), nil
+	}
+
+	products, err := r.allProduct.Fetch(key, r.loadAllProducts, allProductsTags)
+	if err != nil {
+		return nil, err
 	}
 
+	r.l1.set(key, append([]dto.Product(nil), products...))
+	return products, nil
+}
+
+func (r *shopRepo) loadAllProducts() ([]dto.Product, error) {
 	var products []model.Product
 	if err := r.db.Find(&products).Error; err != nil {
 		return nil, err
@@ -308,20 +416,40 @@ func (r *shopRepo) GetAllProduct() ([]dto.Product, error) {
 			StoreID:   p.StoreID,
 			Name:      p.Name,
 			Stock:     p.Stock,
+			Price:     p.Price,
+			Currency:  p.Currency,
 			CreatedAt: p.CreatedAt,
 		})
 	}
 
-	jsonData, _ := json.Marshal(result)
-	if err := r.redis.Set(ctx, "products:all", jsonData, 30*time.Minute); err != nil {
-		return nil, fmt.Errorf("redis: %v", err)
+	return result, nil
+}
+
+func allProductsTags([]dto.Product) []string {
+	return []string{allProductsTag}
+}
+
+// InvalidateTags membuang semua cache (redis dan L1) yang ditag dengan salah satu tag
+func (r *shopRepo) InvalidateTags(tags ...string) error {
+	_, err := invalidateTags(r.redis, r.l1, tags...)
+	return err
+}
+
+// invalidate dipanggil setelah tulis ke mysql berhasil. kegagalan redis hanya dicatat
+// (log dan metrics family tag) supaya write yang sudah commit tidak dilaporkan gagal,
+// cache yang tertinggal tetap habis lewat TTL
+func (r *shopRepo) invalidate(tags ...string) {
+	if err := r.InvalidateTags(tags...); err != nil {
+		log.Println(err)
 	}
+}
 
-	fmt.Println("data dari mysql")
-	return result, nil
+// CacheMetrics mengembalikan counter cache per key family milik proses ini
+func (r *shopRepo) CacheMetrics() []CacheMetrics {
+	return CacheStats()
 }
 
-// penerapan write trough
+// cart dibuang lewat tag user setiap kali berubah, lalu dibangun ulang utuh saat dibaca
 func (r *shopRepo) CreateCartItem(req *dto.CreateCartItemReq) error {
 	newCartItem := model.CartItem{
 		ProductID:      &req.ProductID,
@@ -332,25 +460,7 @@ func (r *shopRepo) CreateCartItem(req *dto.CreateCartItemReq) error {
 		return err
 	}
 
-	key := fmt.Sprintf("user:%d:cartitem:%d:", req.UserID, newCartItem.ID)
-	userCartItemsKey := fmt.Sprintf("user:%d:cartitems", req.UserID)
-	_, err := r.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
-		pipe.HSet(ctx, key, map[string]interface{}{
-			"product_id":      newCartItem.ProductID,
-			"user_id":         newCartItem.UserID,
-			"purchase_amount": newCartItem.PurchaseAmount,
-			"is_paid":         false,
-		})
-		pipe.Expire(ctx, key, 30*time.Minute)
-		pipe.SAdd(ctx, userCartItemsKey, newCartItem.ID)
-		pipe.Expire(ctx, userCartItemsKey, 30*time.Minute)
-
-		return nil
-	})
-	if err != nil {
-		return fmt.Errorf("redis: %v", err)
-	}
-
+	r.invalidate(userTag(req.UserID))
 	return nil
 }
 
@@ -359,54 +469,57 @@ func (r *shopRepo) UpdateAmountCartItem(req *dto.UpdateAmountCartItemReq) error
 		return err
 	}
 
-	key := fmt.Sprintf("user:%d:cartitem:%d:", req.UserID, req.ID)
-	exists, err := r.redis.Exists(ctx, key).Result()
-	if err == nil && exists != 0 {
-		_, err := r.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
-			pipe.HSet(ctx, key,
-				"purchase_amount", req.PurchaseAmount,
-			)
-			return nil
-		})
-		if err != nil {
-			return fmt.Errorf("redis: %v", err)
-		}
-
-	}
-
+	r.invalidate(userTag(req.UserID))
 	return nil
 }
 
 func (r *shopRepo) UpdatePaidCartItem(req *dto.UpdatePaidCartItemReq) error {
-	if err := r.db.Model(&model.CartItem{}).Where("id = ?", req.ID).Update("is_paid", true).Error; err != nil {
+	amounts := map[uint]int{req.ProductID: req.PurchaseAmount}
+	reserved, err := r.reserveStock(amounts)
+	if err != nil {
 		return err
 	}
 
-	key := fmt.Sprintf("user:%d:cartitem:%d:", req.UserID, req.ID)
-	keyQueque := fmt.Sprintf("behind:pending:buy:%d", req.ID)
-	message := fmt.Sprintf("pembelian product dengan id %v /n total item %v", req.ProductID, req.PurchaseAmount)
-
-	exists, err := r.redis.Exists(ctx, key).Result()
-	if err == nil && exists != 0 {
-		_, err := r.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
-			pipe.HSet(ctx, key,
-				"purchase_amount", req.PurchaseAmount,
-				"is_paid", true,
-			)
-			pipe.HSet(ctx, keyQueque, map[string]interface{}{
-				"id":      req.ID,
-				"email":   req.Email,
-				"message": message,
-				"op":      "buy",
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.CartItem{}).
+			Where("id = ? AND user_id = ? AND product_id = ? AND is_paid = ?", req.ID, req.UserID, req.ProductID, false).
+			Updates(map[string]interface{}{
+				"is_paid":         true,
+				"purchase_amount": req.PurchaseAmount,
 			})
-			pipe.Expire(ctx, keyQueque, 10*time.Minute)
-			return nil
-		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return helper.ErrInvalidCartItem
+		}
+
+		if err := decrementStock(tx, amounts); err != nil {
+			return err
+		}
+
+		var product model.Product
+		if err := tx.Select("id", "name", "price", "currency").First(&product, req.ProductID).Error; err != nil {
+			return err
+		}
+
+		subTotal, err := helper.MulMoney(product.Price, req.PurchaseAmount)
 		if err != nil {
-			return fmt.Errorf("redis: %v", err)
+			return err
 		}
+
+		return addOutbox(tx, "buy", receiptMail(tx, req.UserID, req.Email, req.ID, 0, product.Currency, []model.OrderLine{{
+			ProductName:    product.Name,
+			PurchaseAmount: req.PurchaseAmount,
+			SubTotal:       subTotal,
+		}}, req.PurchaseAmount, subTotal))
+	})
+	if err != nil {
+		r.releaseStock(reserved)
+		return err
 	}
 
+	r.invalidate(userTag(req.UserID), productTag(req.ProductID), allProductsTag)
 	return nil
 }
 
@@ -415,66 +528,52 @@ func (r *shopRepo) DeleteCartItem(userId, id uint) error {
 		return err
 	}
 
-	itemKey := fmt.Sprintf("user:%d:cartitem:%d", userId, id)
-	itemsKey := fmt.Sprintf("user:%d:cartitems", userId)
-
-	_, err := r.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
-		pipe.SRem(ctx, itemsKey, id)
-		pipe.Del(ctx, itemKey)
-		return nil
-	})
-
-	if err != nil {
-		return fmt.Errorf("redis: %v", err)
-	}
-
+	r.invalidate(userTag(userId))
 	return nil
 }
 
 func (r *shopRepo) GetMyCartItems(userId uint) ([]dto.CartItem, error) {
-	itemsKey := fmt.Sprintf("user:%d:cartitems", userId)
-	itemIDs, err := r.redis.SMembers(ctx, itemsKey).Result()
-
 	var items []dto.CartItem
 
-	if err == nil || len(itemIDs) != 0 {
-		cmds := make([]*redis.MapStringStringCmd, len(itemIDs))
-		_, err = r.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
-			for i, itemID := range itemIDs {
-				key := fmt.Sprintf("user:%d:cartitem:%s:", userId, itemID)
-				cmds[i] = pipe.HGetAll(ctx, key)
-			}
-			return nil
-		})
-		if err == nil {
-			for i, cmd := range cmds {
-				data, err := cmd.Result()
-				if err == nil || len(data) != 0 {
-					cartItemId, _ := strconv.ParseUint(itemIDs[i], 10, 64)
-					productID, _ := strconv.ParseUint(data["product_id"], 10, 64)
-					userIDParsed, _ := strconv.ParseUint(data["user_id"], 10, 64)
-					purchaseAmount, _ := strconv.Atoi(data["purchase_amount"])
-					isPaid, _ := strconv.ParseBool(data["is_paid"])
-
-					items = append(items, dto.CartItem{
-						ID:             uint(cartItemId),
-						ProductID:      uint(productID),
-						UserID:         uint(userIDParsed),
-						PurchaseAmount: purchaseAmount,
-						IsPaid:         isPaid,
-					})
-				}
-
-			}
+	itemIDs, err := r.redis.SMembers(ctx, cartIndexFamily.Key(userId)).Result()
+	switch {
+	case err != nil:
+		r.cartIndex.fail()
+		log.Println("redis:", err)
+	case len(itemIDs) == 0:
+		r.cartIndex.miss()
+	default:
+		r.cartIndex.hit()
+	}
+
+	if len(itemIDs) != 0 {
+		keys := make([]string, len(itemIDs))
+		for i, itemID := range itemIDs {
+			keys[i] = r.cartItems.Key(userId, itemID)
+		}
 
-			if len(items) > 0 {
-				fmt.Println("data dari redis")
-				return items, nil
+		cached, err := r.cartItems.GetMany(keys)
+		if err == nil {
+			for _, c := range cached {
+				items = append(items, dto.CartItem{
+					ID:               c.ID,
+					ProductID:        c.ProductID,
+					UserID:           c.UserID,
+					PurchaseAmount:   c.PurchaseAmount,
+					IsPaid:           c.IsPaid,
+					IsProductDeleted: c.IsProductDeleted,
+					CreatedAt:        c.CreatedAt,
+				})
 			}
 
+			return items, nil
+		}
+		if !errors.Is(err, ErrCacheMiss) {
+			log.Println(err)
 		}
 	}
 
+	start := time.Now()
 	if err := r.db.Where("user_id = ?", userId).Find(&items).Error; err != nil {
 
 		return nil, err
@@ -484,20 +583,98 @@ func (r *shopRepo) GetMyCartItems(userId uint) ([]dto.CartItem, error) {
 		return nil, helper.ErrUnavaible
 	}
 
-	fmt.Println("data dari mysql (fallback)")
+	if err := r.cacheCartItems(userId, items); err != nil {
+		log.Println(err)
+	} else {
+		r.cartItems.metrics.rebuilt(time.Since(start))
+	}
+
 	return items, nil
 }
 
-func (r *shopRepo) CheckStock(id uint, req int) (bool, error) {
-	key := fmt.Sprintf("product:%d", id)
+// cacheCartItems menyimpan seluruh cart user, item lebih dulu baru index,
+// supaya index tidak pernah menunjuk ke item yang belum ada
+func (r *shopRepo) cacheCartItems(userId uint, items []dto.CartItem) error {
+	keys := make([]string, len(items))
+	entries := make([]cartItemEntry, len(items))
+	ids := make([]interface{}, len(items))
+	for i, item := range items {
+		keys[i] = r.cartItems.Key(userId, item.ID)
+		ids[i] = item.ID
+		entries[i] = newCartItemEntry(item)
+	}
 
-	stockStr, err := r.redis.HGet(ctx, key, "stock").Result()
-	if err == nil {
-		stock, _ := strconv.Atoi(stockStr)
+	if err := r.cartItems.SetMany(keys, entries, cartItemTags); err != nil {
+		return err
+	}
+
+	indexKey := cartIndexFamily.Key(userId)
+	_, err := r.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, indexKey)
+		pipe.SAdd(ctx, indexKey, ids...)
+		pipe.Expire(ctx, indexKey, cartIndexFamily.TTL)
+		addTags(pipe, indexKey, []string{userTag(userId)})
+		return nil
+	})
+	if err != nil {
+		return &CacheError{Key: indexKey, Op: "sadd", Err: err}
+	}
+
+	return nil
+}
+
+// total harga dihitung dari harga product saat ini untuk cart item yang belum dibayar
+func (r *shopRepo) GetMyCartTotal(userId uint) (*dto.CartTotal, error) {
+	var cartItems []model.CartItem
+	if err := r.db.Preload("Product").
+		Where("user_id = ? AND is_paid = ? AND is_product_deleted = ?", userId, false, false).
+		Find(&cartItems).Error; err != nil {
+		return nil, err
+	}
+
+	if len(cartItems) == 0 {
+		return nil, helper.ErrUnavaible
+	}
+
+	var total dto.CartTotal
+	for _, c := range cartItems {
+		if c.Product == nil {
+			continue
+		}
+		if total.Currency == "" {
+			total.Currency = c.Product.Currency
+		}
+		if total.Currency != c.Product.Currency {
+			return nil, helper.ErrMixedCurrency
+		}
+
+		subTotal, err := helper.MulMoney(c.Product.Price, c.PurchaseAmount)
+		if err != nil {
+			return nil, err
+		}
+		total.TotalPrice, err = helper.AddMoney(total.TotalPrice, subTotal)
+		if err != nil {
+			return nil, err
+		}
+		total.TotalItem += c.PurchaseAmount
+	}
+
+	return &total, nil
+}
+
+func (r *shopRepo) CheckStock(id uint, req int) (bool, error) {
+	stock, err := r.redis.HGet(ctx, r.products.Key(id), "stock").Int()
+	switch {
+	case err == nil:
+		r.products.metrics.hit()
 		if req > stock {
-			fmt.Println("check dari redis")
 			return false, helper.ErrStocknotEnough
 		}
+	case errors.Is(err, redis.Nil):
+		r.products.metrics.miss()
+	default:
+		r.products.metrics.fail()
+		log.Println(&CacheError{Key: r.products.Key(id), Op: "hget", Err: err})
 	}
 
 	var stockProduct int
@@ -509,6 +686,202 @@ func (r *shopRepo) CheckStock(id uint, req int) (bool, error) {
 		return false, helper.ErrStocknotEnough
 	}
 
-	fmt.Println("check dari mysql")
 	return true, nil
 }
+
+// checkout mengubah cart item terpilih menjadi order dalam satu transaksi
+func (r *shopRepo) Checkout(req *dto.CheckoutReq) (*dto.Order, error) {
+	var order model.Order
+	var reserved []stockReservation
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// row lock supaya checkout bersamaan atas cart item yang sama menunggu, lalu gagal di cek is_paid
+		var cartItems []model.CartItem
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Product").
+			Where("id IN ? AND user_id = ? AND is_paid = ? AND is_product_deleted = ?", req.CartItemIDs, req.UserID, false, false).
+			Find(&cartItems).Error; err != nil {
+			return err
+		}
+		if len(cartItems) != len(req.CartItemIDs) {
+			return helper.ErrInvalidCartItem
+		}
+
+		order = model.Order{
+			UserID: req.UserID,
+			Status: model.OrderStatusPaid,
+		}
+		amounts := make(map[uint]int)
+		for _, c := range cartItems {
+			if c.Product == nil {
+				return helper.ErrInvalidCartItem
+			}
+			if order.Currency == "" {
+				order.Currency = c.Product.Currency
+			}
+			if order.Currency != c.Product.Currency {
+				return helper.ErrMixedCurrency
+			}
+
+			subTotal, err := helper.MulMoney(c.Product.Price, c.PurchaseAmount)
+			if err != nil {
+				return err
+			}
+			order.TotalPrice, err = helper.AddMoney(order.TotalPrice, subTotal)
+			if err != nil {
+				return err
+			}
+
+			amounts[c.Product.ID] += c.PurchaseAmount
+			order.TotalItem += c.PurchaseAmount
+			order.OrderLine = append(order.OrderLine, model.OrderLine{
+				ProductID:      c.ProductID,
+				ProductName:    c.Product.Name,
+				PurchaseAmount: c.PurchaseAmount,
+				UnitPrice:      c.Product.Price,
+				SubTotal:       subTotal,
+			})
+		}
+
+		var err error
+		reserved, err = r.reserveStock(amounts)
+		if err != nil {
+			return err
+		}
+		if err := decrementStock(tx, amounts); err != nil {
+			return err
+		}
+
+		if err := tx.Create(&order).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&model.CartItem{}).
+			Where("id IN ? AND user_id = ? AND is_paid = ?", req.CartItemIDs, req.UserID, false).
+			Update("is_paid", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(req.CartItemIDs)) {
+			return helper.ErrInvalidCartItem
+		}
+
+		return addOutbox(tx, "buy", receiptMail(tx, req.UserID, req.Email, order.ID, order.ID, order.Currency, order.OrderLine, order.TotalItem, order.TotalPrice))
+	})
+	if err != nil {
+		r.releaseStock(reserved)
+		return nil, err
+	}
+
+	tags := []string{userTag(req.UserID), allProductsTag}
+	for _, line := range order.OrderLine {
+		if line.ProductID != nil {
+			tags = append(tags, productTag(*line.ProductID))
+		}
+	}
+	r.invalidate(tags...)
+
+	response := toOrderDTO(order)
+	return &response, nil
+}
+
+func (r *shopRepo) GetMyOrders(userId uint) ([]dto.Order, error) {
+	var orders []model.Order
+	if err := r.db.Preload("OrderLine").Where("user_id = ?", userId).Order("created_at DESC").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+
+	if len(orders) == 0 {
+		return nil, helper.ErrUnavaible
+	}
+
+	var response []dto.Order
+	for _, o := range orders {
+		response = append(response, toOrderDTO(o))
+	}
+
+	return response, nil
+}
+
+func (r *shopRepo) GetMyOrder(userId, id uint) (*dto.Order, error) {
+	var order model.Order
+	if err := r.db.Preload("OrderLine").Where("id = ? AND user_id = ?", id, userId).First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, helper.ErrUnavaible
+		}
+		return nil, err
+	}
+
+	response := toOrderDTO(order)
+	return &response, nil
+}
+
+// receiptMail menyusun payload email struk pembelian sesuai nama dan locale user
+func receiptMail(tx *gorm.DB, userId uint, email string, id, orderId uint, currency string, lines []model.OrderLine, totalItem int, totalPrice int64) queue.MailPayload {
+	var user model.User
+	if err := tx.Select("username", "locale").Where("id = ?", userId).First(&user).Error; err != nil {
+		log.Println("gagal mengambil data user untuk struk:", err)
+	}
+
+	var mailLines []map[string]interface{}
+	for _, l := range lines {
+		mailLines = append(mailLines, map[string]interface{}{
+			"Name":     l.ProductName,
+			"Amount":   l.PurchaseAmount,
+			"SubTotal": helper.FormatMoney(l.SubTotal, currency),
+		})
+	}
+
+	data := map[string]interface{}{
+		"Name":      user.Username,
+		"Lines":     mailLines,
+		"TotalItem": totalItem,
+		"Total":     helper.FormatMoney(totalPrice, currency),
+	}
+	if orderId != 0 {
+		data["OrderID"] = orderId
+	}
+
+	return queue.MailPayload{
+		ID:     id,
+		Email:  email,
+		Locale: user.Locale,
+		Data:   data,
+	}
+}
+
+func toOrderDTO(order model.Order) dto.Order {
+	lines := make([]dto.OrderLine, 0, len(order.OrderLine))
+	for _, l := range order.OrderLine {
+		var productID uint
+		if l.ProductID != nil {
+			productID = *l.ProductID
+		}
+		lines = append(lines, dto.OrderLine{
+			ID:             l.ID,
+			ProductID:      productID,
+			ProductName:    l.ProductName,
+			PurchaseAmount: l.PurchaseAmount,
+			UnitPrice:      l.UnitPrice,
+			SubTotal:       l.SubTotal,
+		})
+	}
+
+	return dto.Order{
+		ID:         order.ID,
+		UserID:     order.UserID,
+		Status:     order.Status,
+		TotalItem:  order.TotalItem,
+		TotalPrice: order.TotalPrice,
+		Currency:   order.Currency,
+		CreatedAt:  order.CreatedAt,
+		Lines:      lines,
+	}
+}
+
+// WarmCache menjalankan Warmup, dilewati jika warmup lain sedang berjalan
+func (r *shopRepo) WarmCache(opts WarmupOptions) error {
+	if _, err := r.Warmup(opts); err != nil && !errors.Is(err, ErrWarmupRunning) {
+		return err
+	}
+
+	return nil
+}
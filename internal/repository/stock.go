@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"api_shope/model"
+	"api_shope/utils/helper"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// reserveStockScript mengurangi stock di hash product secara atomik.
+// return -2 jika product belum ada di cache, -1 jika stock tidak cukup,
+// selain itu sisa stock setelah dikurangi.
+var reserveStockScript = redis.NewScript(`
+local stock = redis.call("HGET", KEYS[1], "stock")
+if not stock then
+	return -2
+end
+if tonumber(stock) < tonumber(ARGV[1]) then
+	return -1
+end
+return redis.call("HINCRBY", KEYS[1], "stock", -tonumber(ARGV[1]))
+`)
+
+// releaseStockScript mengembalikan stock yang sudah direservasi, hanya jika hash masih ada
+var releaseStockScript = redis.NewScript(`
+if redis.call("HEXISTS", KEYS[1], "stock") == 0 then
+	return 0
+end
+return redis.call("HINCRBY", KEYS[1], "stock", tonumber(ARGV[1]))
+`)
+
+type stockReservation struct {
+	productID uint
+	amount    int
+}
+
+// reserveStock mereservasi stock di redis untuk setiap product (id -> jumlah).
+// product yang tidak ada di cache dilewati, mysql tetap jadi penentu akhir.
+func (r *shopRepo) reserveStock(amounts map[uint]int) ([]stockReservation, error) {
+	var reserved []stockReservation
+	for _, id := range sortedProductIDs(amounts) {
+		key := productFamily.Key(id)
+		result, err := reserveStockScript.Run(ctx, r.redis, []string{key}, amounts[id]).Int()
+		if err != nil {
+			r.releaseStock(reserved)
+			return nil, fmt.Errorf("redis: %v", err)
+		}
+
+		switch result {
+		case -2:
+			continue
+		case -1:
+			r.releaseStock(reserved)
+			return nil, helper.ErrStocknotEnough
+		}
+
+		reserved = append(reserved, stockReservation{productID: id, amount: amounts[id]})
+	}
+
+	return reserved, nil
+}
+
+func (r *shopRepo) releaseStock(reserved []stockReservation) {
+	for _, res := range reserved {
+		key := productFamily.Key(res.productID)
+		if err := releaseStockScript.Run(ctx, r.redis, []string{key}, res.amount).Err(); err != nil {
+			log.Println("gagal mengembalikan stock redis:", err)
+			r.redis.Del(ctx, key)
+		}
+	}
+}
+
+// decrementStock mengurangi stock di mysql hanya jika stock masih cukup
+func decrementStock(tx *gorm.DB, amounts map[uint]int) error {
+	for _, id := range sortedProductIDs(amounts) {
+		result := tx.Model(&model.Product{}).
+			Where("id = ? AND stock >= ?", id, amounts[id]).
+			Update("stock", gorm.Expr("stock - ?", amounts[id]))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return helper.ErrStocknotEnough
+		}
+	}
+
+	return nil
+}
+
+// urutan id yang tetap supaya transaksi bersamaan tidak saling deadlock
+func sortedProductIDs(amounts map[uint]int) []uint {
+	ids := make([]uint, 0, len(amounts))
+	for id := range amounts {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// setiap tag adalah set berisi key cache yang bergantung padanya, misalnya
+// tag:store:3 berisi mystore milik admin store 3 dan semua product di dalamnya.
+// umur set dibuat lebih panjang dari TTL entry mana pun, member yang sudah
+// kedaluwarsa tidak masalah karena DEL pada key yang tidak ada diabaikan.
+const (
+	tagPrefix = "tag:"
+	tagTTL    = 24 * time.Hour
+
+	allProductsTag = "product:all"
+	allStoresTag   = "store:all"
+)
+
+// invalidasi yang gagal dihitung sebagai errors family "tag" di metrics
+var tagMetrics = metricsFor(KeyFamily{Name: "tag", CacheOptions: CacheOptions{TTL: tagTTL}})
+
+func storeTag(id uint) string   { return fmt.Sprintf("store:%d", id) }
+func productTag(id uint) string { return fmt.Sprintf("product:%d", id) }
+func userTag(id uint) string    { return fmt.Sprintf("user:%d", id) }
+
+// addTags mencatat key di setiap tag, dipanggil di pipeline yang sama dengan penulisan key
+func addTags(pipe redis.Pipeliner, key string, tags []string) {
+	for _, tag := range tags {
+		pipe.SAdd(ctx, tagPrefix+tag, key)
+		pipe.Expire(ctx, tagPrefix+tag, tagTTL)
+	}
+}
+
+// invalidateTagsScript menghapus semua key milik tag beserta set tag-nya,
+// lalu mengembalikan daftar key supaya L1 ikut dibersihkan
+var invalidateTagsScript = redis.NewScript(`
+local purged = {}
+for _, tag in ipairs(KEYS) do
+	local keys = redis.call("SMEMBERS", tag)
+	for _, key in ipairs(keys) do
+		redis.call("DEL", key)
+		table.insert(purged, key)
+	end
+	redis.call("DEL", tag)
+end
+return purged
+`)
+
+func invalidateTags(rdb *redis.Client, l1 *LocalCache, tags ...string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	tagKeys := make([]string, len(tags))
+	for i, tag := range tags {
+		tagKeys[i] = tagPrefix + tag
+	}
+
+	keys, err := invalidateTagsScript.Run(ctx, rdb, tagKeys).StringSlice()
+	if err != nil {
+		tagMetrics.fail()
+		return nil, &CacheError{Key: fmt.Sprint(tags), Op: "invalidate", Err: err}
+	}
+	recordEvictions(keys)
+	// redis sudah bersih, L1 instance lain tetap kedaluwarsa sendiri lewat TTL
+	if err := l1.Invalidate(keys...); err != nil {
+		log.Println(err)
+	}
+
+	return keys, nil
+}
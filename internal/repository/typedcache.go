@@ -0,0 +1,287 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrCacheMiss berarti key tidak ada di redis, pemanggil harus membaca mysql
+var ErrCacheMiss = errors.New("cache: miss")
+
+// CacheError menjelaskan kegagalan membaca/menulis cache, termasuk entry yang rusak
+// atau tidak lengkap, supaya tidak diam-diam menjadi nilai nol
+type CacheError struct {
+	Key string
+	Op  string
+	Err error
+}
+
+func (e *CacheError) Error() string {
+	return fmt.Sprintf("cache: %s %s: %v", e.Op, e.Key, e.Err)
+}
+
+func (e *CacheError) Unwrap() error {
+	return e.Err
+}
+
+// KeyFamily adalah sekelompok key dengan format dan umur yang sama. naikkan Version
+// setiap kali bentuk data berubah, entry versi lama tidak dibaca lagi dan habis sendiri.
+type KeyFamily struct {
+	Name    string
+	Version int
+	CacheOptions
+}
+
+// Key menghasilkan <name>:v<version>:<part>:<part>...
+func (f KeyFamily) Key(parts ...interface{}) string {
+	key := fmt.Sprintf("%s:v%d", f.Name, f.Version)
+	for _, p := range parts {
+		key += fmt.Sprint(":", p)
+	}
+
+	return key
+}
+
+// Cache menyimpan T utuh sebagai satu value redis dengan codec pilihan
+type Cache[T any] struct {
+	redis   *redis.Client
+	family  KeyFamily
+	codec   Codec
+	aside   *cacheAside
+	metrics *familyMetrics
+}
+
+func NewCache[T any](redis *redis.Client, family KeyFamily, codec Codec) *Cache[T] {
+	metrics := metricsFor(family)
+	return &Cache[T]{redis, family, codec, newCacheAside(redis, metrics), metrics}
+}
+
+func (c *Cache[T]) Key(parts ...interface{}) string {
+	return c.family.Key(parts...)
+}
+
+// Get mengembalikan ErrCacheMiss jika key tidak ada, atau *CacheError jika gagal dibaca
+func (c *Cache[T]) Get(key string) (T, error) {
+	var value T
+
+	entry, err := c.aside.get(key)
+	if err != nil {
+		c.metrics.fail()
+		return value, err
+	}
+	if entry == nil {
+		c.metrics.miss()
+		return value, ErrCacheMiss
+	}
+
+	if err := c.codec.Unmarshal(entry.Data, &value); err != nil {
+		c.metrics.fail()
+		return value, &CacheError{Key: key, Op: c.codec.Name() + " decode", Err: err}
+	}
+
+	c.metrics.hit()
+	return value, nil
+}
+
+func (c *Cache[T]) Set(key string, value T, tags ...string) error {
+	data, err := c.codec.Marshal(value)
+	if err != nil {
+		return &CacheError{Key: key, Op: c.codec.Name() + " encode", Err: err}
+	}
+
+	raw := cacheEntry{
+		Data:       data,
+		FreshUntil: time.Now().Add(c.family.TTL).UnixMilli(),
+	}.encode()
+	_, err = c.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, raw, c.family.TTL+c.family.Stale)
+		addTags(pipe, key, tags)
+		return nil
+	})
+	if err != nil {
+		return &CacheError{Key: key, Op: "set", Err: err}
+	}
+
+	return nil
+}
+
+// Fetch adalah cache-aside dengan perlindungan stampede (lihat cacheAside).
+// tagsOf (boleh nil) menentukan tag invalidasi dari nilai yang baru dimuat.
+// entry yang tidak bisa didecode dilaporkan, dibuang, lalu dibaca ulang dari load.
+func (c *Cache[T]) Fetch(key string, load func() (T, error), tagsOf func(T) []string) (T, error) {
+	loadEncoded := func() ([]byte, []string, error) {
+		value, err := load()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		data, err := c.codec.Marshal(value)
+		if err != nil {
+			return nil, nil, &CacheError{Key: key, Op: c.codec.Name() + " encode", Err: err}
+		}
+
+		var tags []string
+		if tagsOf != nil {
+			tags = tagsOf(value)
+		}
+		return data, tags, nil
+	}
+
+	var value T
+	data, err := c.aside.fetch(key, c.family.CacheOptions, loadEncoded)
+	if err != nil {
+		return value, err
+	}
+
+	if err := c.codec.Unmarshal(data, &value); err != nil {
+		c.metrics.fail()
+		log.Println(&CacheError{Key: key, Op: c.codec.Name() + " decode", Err: err})
+		c.redis.Del(ctx, key)
+		return load()
+	}
+
+	return value, nil
+}
+
+// HashCache menyimpan T sebagai redis hash dengan nama field dari tag `redis`.
+// dipakai family yang fieldnya diubah langsung, misalnya stock product oleh script lua.
+type HashCache[T any] struct {
+	redis   *redis.Client
+	family  KeyFamily
+	fields  []string
+	metrics *familyMetrics
+}
+
+func NewHashCache[T any](redis *redis.Client, family KeyFamily) *HashCache[T] {
+	var zero T
+	return &HashCache[T]{redis, family, redisFields(reflect.TypeOf(zero)), metricsFor(family)}
+}
+
+func redisFields(typ reflect.Type) []string {
+	var fields []string
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("redis"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+
+	return fields
+}
+
+func (c *HashCache[T]) Key(parts ...interface{}) string {
+	return c.family.Key(parts...)
+}
+
+func (c *HashCache[T]) Get(key string) (*T, error) {
+	data, err := c.redis.HGetAll(ctx, key).Result()
+	if err != nil {
+		c.metrics.fail()
+		return nil, &CacheError{Key: key, Op: "hgetall", Err: err}
+	}
+
+	value, err := c.decode(key, data)
+	c.record(err, 1)
+	return value, err
+}
+
+// record menghitung hasil satu lookup yang mencakup n key
+func (c *HashCache[T]) record(err error, n int) {
+	switch {
+	case err == nil:
+		c.metrics.hits.Add(int64(n))
+	case errors.Is(err, ErrCacheMiss):
+		c.metrics.miss()
+	default:
+		c.metrics.fail()
+	}
+}
+
+// GetMany membaca beberapa hash sekaligus. jika satu saja hilang hasilnya ErrCacheMiss,
+// supaya pemanggil tidak mengembalikan list yang kurang lengkap
+func (c *HashCache[T]) GetMany(keys []string) ([]T, error) {
+	cmds := make([]*redis.MapStringStringCmd, len(keys))
+	_, err := c.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.HGetAll(ctx, key)
+		}
+		return nil
+	})
+	if err != nil {
+		c.metrics.fail()
+		return nil, &CacheError{Key: c.family.Key("*"), Op: "hgetall", Err: err}
+	}
+
+	values := make([]T, 0, len(keys))
+	for i, cmd := range cmds {
+		value, err := c.decode(keys[i], cmd.Val())
+		if err != nil {
+			c.record(err, 1)
+			return nil, err
+		}
+		values = append(values, *value)
+	}
+
+	c.record(nil, len(keys))
+	return values, nil
+}
+
+func (c *HashCache[T]) decode(key string, data map[string]string) (*T, error) {
+	if len(data) == 0 {
+		return nil, ErrCacheMiss
+	}
+
+	for _, field := range c.fields {
+		if _, ok := data[field]; !ok {
+			return nil, &CacheError{Key: key, Op: "decode", Err: fmt.Errorf("field %q tidak ada", field)}
+		}
+	}
+
+	var value T
+	if err := redis.NewMapStringStringResult(data, nil).Scan(&value); err != nil {
+		return nil, &CacheError{Key: key, Op: "decode", Err: err}
+	}
+
+	return &value, nil
+}
+
+func (c *HashCache[T]) Set(key string, value T, tags ...string) error {
+	_, err := c.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key, value)
+		pipe.Expire(ctx, key, c.family.TTL)
+		addTags(pipe, key, tags)
+		return nil
+	})
+	if err != nil {
+		return &CacheError{Key: key, Op: "hset", Err: err}
+	}
+
+	return nil
+}
+
+// SetMany menulis beberapa hash dalam satu transaksi, tagsOf (boleh nil) menentukan tag tiap entry
+func (c *HashCache[T]) SetMany(keys []string, values []T, tagsOf func(T) []string) error {
+	_, err := c.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			pipe.Del(ctx, key)
+			pipe.HSet(ctx, key, values[i])
+			pipe.Expire(ctx, key, c.family.TTL)
+			if tagsOf != nil {
+				addTags(pipe, key, tagsOf(values[i]))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return &CacheError{Key: c.family.Key("*"), Op: "hset", Err: err}
+	}
+
+	return nil
+}
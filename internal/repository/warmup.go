@@ -0,0 +1,262 @@
+package repository
+
+import (
+	"api_shope/model"
+	"api_shope/utils/helper"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// hanya satu warmup berjalan di seluruh instance (boot, job malam, atau cmd/warmcache)
+	warmupLockKey = "lock:warmup"
+	warmupLockTTL = 10 * time.Minute
+
+	defaultWarmupBatch = 500
+	defaultHotStores   = 20
+	// store terlaris dihitung dari penjualan selama rentang ini
+	hotStoreWindow = 30 * 24 * time.Hour
+)
+
+var ErrWarmupRunning = errors.New("cache: warmup sedang berjalan di instance lain")
+
+// lock warmup diperpanjang setiap batch selama masih dipegang token ini
+var renewWarmupScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// WarmupOptions mengatur beban warmup ke mysql. QPS membatasi jumlah query per detik
+// (satu batch product = satu query), 0 berarti tanpa batas. BatchSize dan HotStores
+// bernilai 0 memakai bawaan, HotStores negatif melewati mystore.
+type WarmupOptions struct {
+	BatchSize int
+	QPS       float64
+	HotStores int
+	Progress  func(stage string, done, total int)
+}
+
+type WarmupResult struct {
+	Products  int
+	HotStores int
+	Duration  time.Duration
+}
+
+// pacer menjaga jarak minimal antar query mysql
+type pacer struct {
+	interval time.Duration
+	next     time.Time
+}
+
+func newPacer(qps float64) *pacer {
+	if qps <= 0 {
+		return &pacer{}
+	}
+
+	return &pacer{interval: time.Duration(float64(time.Second) / qps)}
+}
+
+func (p *pacer) wait() {
+	if p.interval <= 0 {
+		return
+	}
+	if d := time.Until(p.next); d > 0 {
+		time.Sleep(d)
+	}
+	p.next = time.Now().Add(p.interval)
+}
+
+// Warmup mengisi ulang list product, list store, hash setiap product dan mystore
+// milik store terlaris langsung dari mysql, tanpa menunggu request pertama
+func (r *shopRepo) Warmup(opts WarmupOptions) (*WarmupResult, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultWarmupBatch
+	}
+	if opts.HotStores < 0 {
+		opts.HotStores = 0
+	} else if opts.HotStores == 0 {
+		opts.HotStores = defaultHotStores
+	}
+	if opts.Progress == nil {
+		opts.Progress = func(string, int, int) {}
+	}
+
+	token, err := helper.GenerateRandomToken(8)
+	if err != nil {
+		return nil, err
+	}
+	locked, err := r.redis.SetNX(ctx, warmupLockKey, token, warmupLockTTL).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis: %v", err)
+	}
+	if !locked {
+		return nil, ErrWarmupRunning
+	}
+	defer releaseLockScript.Run(ctx, r.redis, []string{warmupLockKey}, token)
+
+	start := time.Now()
+	result := &WarmupResult{}
+	pace := newPacer(opts.QPS)
+
+	if err := r.warmLists(pace, opts.Progress); err != nil {
+		return nil, err
+	}
+
+	result.Products, err = r.warmProducts(pace, opts, token)
+	if err != nil {
+		return nil, err
+	}
+
+	result.HotStores, err = r.warmHotStores(pace, opts, token)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// renewWarmupLock gagal jika lock sudah kedaluwarsa dan mungkin dipegang instance lain
+func (r *shopRepo) renewWarmupLock(token string) error {
+	renewed, err := renewWarmupScript.Run(ctx, r.redis, []string{warmupLockKey}, token, warmupLockTTL.Milliseconds()).Int()
+	if err != nil {
+		return fmt.Errorf("redis: %v", err)
+	}
+	if renewed == 0 {
+		return errors.New("cache: lock warmup sudah lepas")
+	}
+
+	return nil
+}
+
+func (r *shopRepo) warmLists(pace *pacer, progress func(string, int, int)) error {
+	pace.wait()
+	start := time.Now()
+	products, err := r.loadAllProducts()
+	if err != nil {
+		return err
+	}
+	if err := r.allProduct.Set(r.allProduct.Key("all"), products, allProductsTags(products)...); err != nil {
+		return err
+	}
+	r.allProduct.metrics.rebuilt(time.Since(start))
+	progress("products:all", 1, 1)
+
+	pace.wait()
+	start = time.Now()
+	stores, err := r.loadAllStores()
+	if err != nil {
+		return err
+	}
+	if err := r.allStore.Set(r.allStore.Key("all"), stores, allStoresTags(stores)...); err != nil {
+		return err
+	}
+	r.allStore.metrics.rebuilt(time.Since(start))
+	progress("stores:all", 1, 1)
+
+	// list di L1 instance lain dibuang supaya ikut membaca hasil warmup
+	if err := r.l1.Invalidate(r.allProduct.Key("all")); err != nil {
+		log.Println(err)
+	}
+
+	return nil
+}
+
+// warmProducts menulis hash product per batch dengan keyset pagination,
+// setiap batch ditulis dalam satu pipeline
+func (r *shopRepo) warmProducts(pace *pacer, opts WarmupOptions, token string) (int, error) {
+	var total int64
+	if err := r.db.Model(&model.Product{}).Count(&total).Error; err != nil {
+		return 0, err
+	}
+
+	var lastID uint
+	done := 0
+	for {
+		if err := r.renewWarmupLock(token); err != nil {
+			return done, err
+		}
+		pace.wait()
+		var batch []model.Product
+		if err := r.db.Where("id > ?", lastID).Order("id").Limit(opts.BatchSize).Find(&batch).Error; err != nil {
+			return done, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		keys := make([]string, len(batch))
+		entries := make([]productEntry, len(batch))
+		for i, p := range batch {
+			keys[i] = r.products.Key(p.ID)
+			entries[i] = newProductEntry(p)
+		}
+		if err := r.products.SetMany(keys, entries, productEntryTags); err != nil {
+			return done, err
+		}
+		if err := r.l1.Invalidate(keys...); err != nil {
+			log.Println(err)
+		}
+
+		done += len(batch)
+		lastID = batch[len(batch)-1].ID
+		opts.Progress("product", done, int(total))
+
+		if len(batch) < opts.BatchSize {
+			break
+		}
+	}
+
+	return done, nil
+}
+
+type hotStore struct {
+	StoreID uint
+	AdminID uint
+	Sold    int
+}
+
+func (r *shopRepo) warmHotStores(pace *pacer, opts WarmupOptions, token string) (int, error) {
+	if opts.HotStores == 0 {
+		return 0, nil
+	}
+
+	pace.wait()
+	var stores []hotStore
+	if err := r.db.Table("order_lines").
+		Select("products.store_id, stores.admin_id, SUM(order_lines.purchase_amount) AS sold").
+		Joins("JOIN products ON products.id = order_lines.product_id").
+		Joins("JOIN stores ON stores.id = products.store_id").
+		Where("order_lines.created_at >= ?", time.Now().Add(-hotStoreWindow)).
+		Group("products.store_id, stores.admin_id").
+		Order("sold DESC").
+		Limit(opts.HotStores).
+		Scan(&stores).Error; err != nil {
+		return 0, err
+	}
+
+	for i, s := range stores {
+		if err := r.renewWarmupLock(token); err != nil {
+			return i, err
+		}
+		pace.wait()
+		start := time.Now()
+		store, err := r.loadMyStore(s.AdminID)
+		if err != nil {
+			return i, err
+		}
+		if err := r.myStore.Set(r.myStore.Key(s.AdminID), store, myStoreTags(store)...); err != nil {
+			return i, err
+		}
+		r.myStore.metrics.rebuilt(time.Since(start))
+		opts.Progress("mystore", i+1, len(stores))
+	}
+
+	return len(stores), nil
+}
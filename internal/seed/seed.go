@@ -0,0 +1,254 @@
+package seed
+
+import (
+	"api_shope/internal/queue"
+	"api_shope/model"
+	"api_shope/utils/helper"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// semua user seed memakai domain ini supaya mudah dikenali dan tidak bentrok dengan user asli
+const emailDomain = "seed.local"
+
+const batchSize = 500
+
+var ErrAlreadySeeded = errors.New("seed: data seed sudah ada, jalankan dengan reset")
+
+// tabel dikosongkan berurutan dari yang paling bergantung, schema_migrations tidak disentuh
+var resetTables = []string{"outboxes", "order_lines", "orders", "cart_items", "products", "stores", "users"}
+
+// key redis yang ikut dibuang saat reset: queue, dedupe outbox dan token auth.
+// lock leader scheduler tidak disentuh supaya worker yang berjalan tetap normal
+var resetKeyPatterns = []string{
+	queue.StreamKey,
+	queue.DelayedKey + "*",
+	queue.DeadKey,
+	"behind:job:*",
+	"behind:outbox:*",
+	"behind:cart_reminder:*",
+	"behind:scheduler:fired:*",
+	"verify:*",
+	"reset:password:*",
+	"refresh:*",
+	"jwt:*",
+}
+
+const scanCount = 500
+
+// created_at data seed dihitung mundur dari waktu ini jika BaseTime kosong
+var defaultBaseTime = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Options mengatur skala data. nilai Seed dan BaseTime yang sama selalu menghasilkan data yang sama.
+type Options struct {
+	Seed             uint64
+	BaseTime         time.Time
+	Users            int
+	Stores           int
+	ProductsPerStore int
+	CartItemsPerUser int
+	Password         string
+}
+
+type Result struct {
+	Users     int
+	Stores    int
+	Products  int
+	CartItems int
+}
+
+var (
+	adjectives = []string{"Maju", "Jaya", "Sentosa", "Makmur", "Berkah", "Abadi", "Sejahtera", "Mandiri", "Lestari", "Indah"}
+	nouns      = []string{"Mart", "Store", "Shop", "Grosir", "Toko", "Niaga", "Market", "Outlet"}
+	products   = []string{"Kopi", "Teh", "Beras", "Gula", "Minyak", "Sabun", "Sampo", "Mie", "Susu", "Roti", "Kecap", "Sarden", "Biskuit", "Madu", "Keju"}
+	variants   = []string{"Original", "Premium", "Spesial", "Hemat", "Jumbo", "Mini", "Organik", "Pedas"}
+	locales    = []string{"id", "en"}
+)
+
+func (o Options) validate() error {
+	switch {
+	case o.Users < 0 || o.Stores < 0 || o.ProductsPerStore < 0 || o.CartItemsPerUser < 0:
+		return errors.New("seed: jumlah tidak boleh negatif")
+	case o.Stores > o.Users:
+		return errors.New("seed: setiap store butuh satu admin, jumlah store tidak boleh melebihi user")
+	case o.Users > 0 && o.Password == "":
+		return errors.New("seed: password kosong")
+	}
+
+	return nil
+}
+
+// Run mengisi database dalam satu transaksi. user ke-1 sampai ke-Stores menjadi admin store.
+func Run(db *gorm.DB, opts Options) (*Result, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	var existing int64
+	if err := db.Model(&model.User{}).Where("email LIKE ?", "%@"+emailDomain).Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, ErrAlreadySeeded
+	}
+
+	// bcrypt lambat, semua user seed berbagi satu hash
+	password, err := helper.HashPasswrd(opts.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.BaseTime.IsZero() {
+		opts.BaseTime = defaultBaseTime
+	}
+
+	rng := rand.New(rand.NewPCG(opts.Seed, opts.Seed))
+	result := &Result{}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		users := make([]model.User, opts.Users)
+		for i := range users {
+			users[i] = model.User{
+				Username: fmt.Sprintf("user%04d", i+1),
+				Email:    fmt.Sprintf("user%04d@%s", i+1, emailDomain),
+				Password: password,
+				Verified: true,
+				Locale:   locales[rng.IntN(len(locales))],
+			}
+		}
+		if err := createInBatches(tx, &users); err != nil {
+			return err
+		}
+		result.Users = len(users)
+
+		stores := make([]model.Store, opts.Stores)
+		for i := range stores {
+			stores[i] = model.Store{
+				// nomor urut menjamin nama unik walaupun kombinasi kata terulang
+				Name:    fmt.Sprintf("%s %s %04d", adjectives[rng.IntN(len(adjectives))], nouns[rng.IntN(len(nouns))], i+1),
+				AdminID: users[i].ID,
+			}
+		}
+		if err := createInBatches(tx, &stores); err != nil {
+			return err
+		}
+		result.Stores = len(stores)
+
+		var items []model.Product
+		for _, store := range stores {
+			for j := 0; j < opts.ProductsPerStore; j++ {
+				items = append(items, model.Product{
+					Name:     products[rng.IntN(len(products))] + " " + variants[rng.IntN(len(variants))],
+					Stock:    rng.IntN(201),
+					Price:    int64(10+rng.IntN(991)) * 500 * 100, // Rp5.000 - Rp500.000 dalam minor unit
+					Currency: helper.DefaultCurrency,
+					StoreID:  store.ID,
+				})
+			}
+		}
+		if err := createInBatches(tx, &items); err != nil {
+			return err
+		}
+		result.Products = len(items)
+
+		if len(items) == 0 {
+			return nil
+		}
+
+		// created_at disebar ke 72 jam sebelum BaseTime, isi BaseTime dengan waktu sekarang
+		// supaya job pengingat cart punya data
+		var cartItems []model.CartItem
+		for _, user := range users {
+			for j := 0; j < opts.CartItemsPerUser; j++ {
+				productID := items[rng.IntN(len(items))].ID
+				cartItems = append(cartItems, model.CartItem{
+					UserID:         user.ID,
+					ProductID:      &productID,
+					PurchaseAmount: 1 + rng.IntN(5),
+					CreatedAt:      opts.BaseTime.Add(-time.Duration(rng.IntN(72*60)) * time.Minute),
+				})
+			}
+		}
+		if err := createInBatches(tx, &cartItems); err != nil {
+			return err
+		}
+		result.CartItems = len(cartItems)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func createInBatches[T any](tx *gorm.DB, rows *[]T) error {
+	if len(*rows) == 0 {
+		return nil
+	}
+
+	// relasi (User.Store, CartItem.User, ...) sudah diisi lewat id, jangan ikut disimpan
+	return tx.Omit(clause.Associations).CreateInBatches(rows, batchSize).Error
+}
+
+// Reset mengosongkan semua tabel aplikasi dan mengembalikan auto increment ke 1
+func Reset(db *gorm.DB) error {
+	// FOREIGN_KEY_CHECKS berlaku per koneksi
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SET FOREIGN_KEY_CHECKS = 0").Error; err != nil {
+			return err
+		}
+		defer conn.Exec("SET FOREIGN_KEY_CHECKS = 1")
+
+		for _, table := range resetTables {
+			if err := conn.Exec("TRUNCATE TABLE `" + table + "`").Error; err != nil {
+				return fmt.Errorf("seed: truncate %s: %v", table, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// ResetRedis membuang key queue, dedupe outbox dan token yang merujuk ke id lama.
+// consumer group dibuat ulang supaya worker yang sedang berjalan tetap bisa membaca stream
+func ResetRedis(rdb *redis.Client) (int, error) {
+	ctx := context.Background()
+
+	cleared := 0
+	for _, match := range resetKeyPatterns {
+		var cursor uint64
+		for {
+			keys, next, err := rdb.Scan(ctx, cursor, match, scanCount).Result()
+			if err != nil {
+				return cleared, fmt.Errorf("redis: %v", err)
+			}
+			if len(keys) > 0 {
+				if err := rdb.Del(ctx, keys...).Err(); err != nil {
+					return cleared, fmt.Errorf("redis: %v", err)
+				}
+				cleared += len(keys)
+			}
+
+			if next == 0 {
+				break
+			}
+			cursor = next
+		}
+	}
+
+	err := rdb.XGroupCreateMkStream(ctx, queue.StreamKey, queue.GroupName, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return cleared, fmt.Errorf("redis: %v", err)
+	}
+
+	return cleared, nil
+}
@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"api_shope/internal/queue"
+	"api_shope/internal/repository"
+	"api_shope/utils/helper"
+	"api_shope/utils/mailer"
+)
+
+type AdminUsecase interface {
+	ListDeadJobs() ([]queue.DeadJob, error)
+	ReplayDeadJob(id string) error
+	GetQueueStats() ([]queue.QueueStats, error)
+	GetJob(id string) (*queue.JobStatus, error)
+	RetryJob(id string) error
+	DiscardJob(id string) error
+
+	//cache
+	GetCacheMetrics() []repository.CacheMetrics
+
+	//mail
+	PreviewMail(template, locale string) (*mailer.Rendered, error)
+}
+
+type adminUsecase struct {
+	queueRepo repository.QueueRepo
+	shopRepo  repository.ShopRepo
+	renderer  *mailer.Renderer
+}
+
+func NewAdminUsecase(queueRepo repository.QueueRepo, shopRepo repository.ShopRepo, renderer *mailer.Renderer) AdminUsecase {
+	return &adminUsecase{queueRepo, shopRepo, renderer}
+}
+
+func (u *adminUsecase) ListDeadJobs() ([]queue.DeadJob, error) {
+	return u.queueRepo.ListDeadJobs()
+}
+
+func (u *adminUsecase) ReplayDeadJob(id string) error {
+	return u.queueRepo.ReplayDeadJob(id)
+}
+
+func (u *adminUsecase) GetQueueStats() ([]queue.QueueStats, error) {
+	return u.queueRepo.GetQueueStats()
+}
+
+func (u *adminUsecase) GetJob(id string) (*queue.JobStatus, error) {
+	return u.queueRepo.GetJob(id)
+}
+
+func (u *adminUsecase) RetryJob(id string) error {
+	return u.queueRepo.RetryJob(id)
+}
+
+func (u *adminUsecase) DiscardJob(id string) error {
+	return u.queueRepo.DiscardJob(id)
+}
+
+func (u *adminUsecase) GetCacheMetrics() []repository.CacheMetrics {
+	return u.shopRepo.CacheMetrics()
+}
+
+// PreviewMail merender template dengan data contoh
+func (u *adminUsecase) PreviewMail(template, locale string) (*mailer.Rendered, error) {
+	for _, name := range mailer.Templates {
+		if name == template {
+			return u.renderer.Render(template, locale, mailer.SampleData(template, locale))
+		}
+	}
+
+	return nil, helper.ErrUnavaible
+}
@@ -3,21 +3,41 @@ package usecase
 import (
 	"api_shope/dto"
 	"api_shope/internal/repository"
+	"api_shope/model"
 	"api_shope/utils/helper"
+	"api_shope/utils/mailer"
 	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
 )
 
 type AuthUsecase interface {
 	Register(req *dto.RegisterReq) error
-	Login(req *dto.LoginReq) (string, error)
+	VerifyEmail(token string) error
+	ResendVerification(userId uint) error
+	ForgotPassword(req *dto.ForgotPasswordReq) error
+	ResetPassword(req *dto.ResetPasswordReq) error
+	ChangePassword(req *dto.ChangePasswordReq) error
+	Login(req *dto.LoginReq) (*dto.TokenPair, error)
+	Refresh(req *dto.RefreshReq) (*dto.TokenPair, error)
+	Logout(claims *helper.JWTCLAIMS, req *dto.RefreshReq) error
 }
 
 type authUsecase struct {
 	authRepo repository.AuthRepo
+	jwt      *helper.JWT
+	appURL   string
+	// halaman form reset password di frontend, api hanya menerima POST /password/reset
+	resetURL string
 }
 
-func NewAuthUsecase(authRepo repository.AuthRepo) AuthUsecase {
-	return &authUsecase{authRepo}
+func NewAuthUsecase(authRepo repository.AuthRepo, jwt *helper.JWT, appURL, resetURL string) AuthUsecase {
+	return &authUsecase{authRepo, jwt, strings.TrimRight(appURL, "/"), resetURL}
 }
 
 func (u *authUsecase) Register(req *dto.RegisterReq) error {
@@ -25,36 +45,288 @@ func (u *authUsecase) Register(req *dto.RegisterReq) error {
 	if !valid {
 		return helper.ErrInvalidEmail
 	}
+	req.Locale = mailer.NormalizeLocale(req.Locale)
 	hashsed, err := helper.HashPasswrd(req.Password)
 	if err != nil {
 		return err
 	}
 
 	req.Password = hashsed
-	if err := u.authRepo.Register(req); err != nil {
+	user, err := u.authRepo.Register(req)
+	if err != nil {
 		return err
 	}
+
+	// user sudah tersimpan, jadi gagal kirim verifikasi tidak menggagalkan register.
+	// token verifikasi ada di redis sehingga tidak bisa ikut transaksi,
+	// user tetap bisa meminta ulang lewat resend verification
+	if err := u.sendVerification(user); err != nil {
+		log.Printf("auth: gagal mengirim verifikasi user %d: %v", user.ID, err)
+	}
+
 	return nil
 }
 
-func (u *authUsecase) Login(req *dto.LoginReq) (string, error) {
+// yang disimpan di redis hanya hash dari token
+func (u *authUsecase) sendVerification(user *model.User) error {
+	token, err := u.newUserToken(user.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := u.authRepo.SaveVerifyToken(user.ID, helper.HashToken(token)); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", u.appURL, url.QueryEscape(token))
+	return u.authRepo.EnqueueMail("verify", user, map[string]interface{}{
+		"Link":      link,
+		"ExpiresIn": mailer.FormatDuration(repository.VerifyTokenTTL, user.Locale),
+	})
+}
+
+func (u *authUsecase) VerifyEmail(token string) error {
+	userId, err := u.userIDFromToken(token)
+	if err != nil {
+		return err
+	}
+
+	consumed, err := u.authRepo.ConsumeVerifyToken(userId, helper.HashToken(token))
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return helper.ErrInvalidToken
+	}
+
+	return u.authRepo.MarkVerified(userId)
+}
+
+// newUserToken membuat token bertanda tangan untuk user, formatnya "<user id>.<random>.<hmac>"
+func (u *authUsecase) newUserToken(userId uint) (string, error) {
+	random, err := helper.GenerateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	return u.jwt.SignToken(fmt.Sprintf("%d.%s", userId, random)), nil
+}
+
+func (u *authUsecase) userIDFromToken(token string) (uint, error) {
+	payload, ok := u.jwt.VerifySignedToken(token)
+	if !ok {
+		return 0, helper.ErrInvalidToken
+	}
+
+	idPart, _, found := strings.Cut(payload, ".")
+	if !found {
+		return 0, helper.ErrInvalidToken
+	}
+	userId, err := strconv.ParseUint(idPart, 10, 64)
+	if err != nil {
+		return 0, helper.ErrInvalidToken
+	}
+
+	return uint(userId), nil
+}
+
+func (u *authUsecase) ResendVerification(userId uint) error {
+	user, err := u.authRepo.GetUserByID(userId)
+	if err != nil {
+		return err
+	}
+	if user.Verified {
+		return helper.ErrAlreadyVerified
+	}
+
+	allowed, err := u.authRepo.AllowResendVerify(userId)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return helper.ErrTooManyRequest
+	}
+
+	return u.sendVerification(user)
+}
+
+func (u *authUsecase) Login(req *dto.LoginReq) (*dto.TokenPair, error) {
 	valid := helper.IsValidEmail(req.Email)
 	if !valid {
-		return "", helper.ErrInvalidEmail
+		return nil, helper.ErrInvalidEmail
 	}
 	user, err := u.authRepo.LoginEmail(req.Email)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if valid := helper.ComparePassword(user.Password, req.Password); !valid {
-		return "", errors.New("email dan password tidak cocok")
+		return nil, errors.New("email dan password tidak cocok")
+	}
+
+	family, err := helper.GenerateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.issueTokenPair(user.Email, user.ID, family)
+}
+
+// refresh token hanya bisa dipakai sekali, setiap refresh menghasilkan pasangan token baru
+func (u *authUsecase) Refresh(req *dto.RefreshReq) (*dto.TokenPair, error) {
+	if req.RefreshToken == "" {
+		return nil, helper.ErrInvalidToken
+	}
+
+	session, err := u.authRepo.UseRefreshToken(helper.HashToken(req.RefreshToken))
+	if err != nil {
+		return nil, err
+	}
+
+	return u.issueTokenPair(session.Email, session.UserID, session.Family)
+}
+
+func (u *authUsecase) Logout(claims *helper.JWTCLAIMS, req *dto.RefreshReq) error {
+	if err := u.authRepo.RevokeAccessToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+		return err
+	}
+
+	if req.RefreshToken == "" {
+		return nil
 	}
 
-	jwt, err := helper.GenerateJWT(user.Email, user.ID)
+	session, err := u.authRepo.GetRefreshSession(helper.HashToken(req.RefreshToken))
+	if err != nil {
+		return err
+	}
+	if session.UserID != claims.UserID {
+		return helper.ErrInvalidToken
+	}
+
+	return u.authRepo.RevokeFamily(session.Family)
+}
+
+func (u *authUsecase) issueTokenPair(email string, userId uint, family string) (*dto.TokenPair, error) {
+	accessToken, claims, err := u.jwt.GenerateJWT(email, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	session := dto.RefreshSession{
+		UserID:    userId,
+		Email:     email,
+		Family:    family,
+		AccessJTI: claims.ID,
+		AccessExp: claims.ExpiresAt.Time,
+	}
+	if err := u.authRepo.SaveRefreshToken(helper.HashToken(refreshToken), &session); err != nil {
+		return nil, err
+	}
+
+	return &dto.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(u.jwt.AccessTTL.Seconds()),
+	}, nil
+}
+
+// selalu sukses walau email tidak terdaftar, supaya tidak membocorkan data user
+func (u *authUsecase) ForgotPassword(req *dto.ForgotPasswordReq) error {
+	if !helper.IsValidEmail(req.Email) {
+		return helper.ErrInvalidEmail
+	}
+
+	user, err := u.authRepo.LoginEmail(req.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, err := u.newUserToken(user.ID)
+	if err != nil {
+		return err
+	}
+	if err := u.authRepo.SaveResetToken(user.ID, helper.HashToken(token)); err != nil {
+		return err
+	}
+
+	link, err := withQuery(u.resetURL, "token", token)
+	if err != nil {
+		return err
+	}
+	return u.authRepo.EnqueueMail("reset", user, map[string]interface{}{
+		"Link":      link,
+		"ExpiresIn": mailer.FormatDuration(repository.ResetTokenTTL, user.Locale),
+	})
+}
+
+func (u *authUsecase) ResetPassword(req *dto.ResetPasswordReq) error {
+	if len(req.NewPassword) < 8 {
+		return helper.ErrWeakPassword
+	}
+
+	userId, err := u.userIDFromToken(req.Token)
+	if err != nil {
+		return err
+	}
+
+	consumed, err := u.authRepo.ConsumeResetToken(userId, helper.HashToken(req.Token))
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return helper.ErrInvalidToken
+	}
+
+	return u.setPassword(userId, req.NewPassword)
+}
+
+func (u *authUsecase) ChangePassword(req *dto.ChangePasswordReq) error {
+	if len(req.NewPassword) < 8 {
+		return helper.ErrWeakPassword
+	}
+
+	user, err := u.authRepo.GetUserByID(req.UserID)
+	if err != nil {
+		return err
+	}
+	if !helper.ComparePassword(user.Password, req.OldPassword) {
+		return helper.ErrWrongPassword
+	}
+
+	return u.setPassword(user.ID, req.NewPassword)
+}
+
+// ganti password lalu cabut semua sesi yang masih aktif
+func (u *authUsecase) setPassword(userId uint, password string) error {
+	hashed, err := helper.HashPasswrd(password)
+	if err != nil {
+		return err
+	}
+
+	if err := u.authRepo.UpdatePassword(userId, hashed); err != nil {
+		return err
+	}
+
+	return u.authRepo.RevokeAllSessions(userId)
+}
+
+// withQuery menambahkan parameter ke url tanpa membuang query yang sudah ada
+func withQuery(rawURL, key, value string) (string, error) {
+	u, err := url.Parse(rawURL)
 	if err != nil {
 		return "", err
 	}
 
-	return jwt, nil
+	q := u.Query()
+	q.Set(key, value)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
 }
@@ -4,6 +4,7 @@ import (
 	"api_shope/dto"
 	"api_shope/internal/repository"
 	"api_shope/utils/helper"
+	"strings"
 )
 
 type ShopUsecase interface {
@@ -24,18 +25,43 @@ type ShopUsecase interface {
 
 	//cartItem
 	GetMyCartItems(userId uint) ([]dto.CartItem, error)
+	GetMyCartTotal(userId uint) (*dto.CartTotal, error)
 	CreateCartItem(req *dto.CreateCartItemReq) error
 	UpdateAmountCartItem(req *dto.UpdateAmountCartItemReq) error
 	UpdatePaidCartItem(req *dto.UpdatePaidCartItemReq) error
 	DeleteCartItem(userId, id uint) error
+
+	//order
+	Checkout(req *dto.CheckoutReq) (*dto.Order, error)
+	GetMyOrders(userId uint) ([]dto.Order, error)
+	GetMyOrder(userId, id uint) (*dto.Order, error)
 }
 
 type shopUsecase struct {
 	shopRepo repository.ShopRepo
+
+	// jika true, user dengan email belum terverifikasi tidak bisa membeli
+	requireVerified bool
+}
+
+func NewShopUsecase(shopRepo repository.ShopRepo, requireVerified bool) ShopUsecase {
+	return &shopUsecase{shopRepo, requireVerified}
 }
 
-func NewShopUsecase(shopRepo repository.ShopRepo) ShopUsecase {
-	return &shopUsecase{shopRepo}
+func (u *shopUsecase) checkVerified(userId uint) error {
+	if !u.requireVerified {
+		return nil
+	}
+
+	verified, err := u.shopRepo.IsUserVerified(userId)
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return helper.ErrEmailNotVerified
+	}
+
+	return nil
 }
 
 func (u *shopUsecase) GetMyStore(userId, storeId uint) (*dto.StoreAndProduct, error) {
@@ -93,13 +119,52 @@ func (u *shopUsecase) CreateProduct(req *dto.CreateProductReq) error {
 		return helper.ErrNotAdmin
 	}
 
+	currency, err := validatePrice(req.Price, req.Currency)
+	if err != nil {
+		return err
+	}
+
+	req.Currency = currency
 	return u.shopRepo.CreateProduct(req)
 }
 
+// harga dan mata uang hanya diubah jika dikirim. mata uang tidak diisi default di sini,
+// dan mengganti mata uang wajib disertai harga karena harga disimpan dalam minor unit
 func (u *shopUsecase) UpdateProduct(req *dto.UpdateProductReq) error {
+	if req.Price != nil && *req.Price < 0 {
+		return helper.ErrInvalidPrice
+	}
+	if req.Currency != nil {
+		if req.Price == nil {
+			return helper.ErrPriceRequired
+		}
+		currency := strings.ToUpper(strings.TrimSpace(*req.Currency))
+		if !helper.IsValidCurrency(currency) {
+			return helper.ErrInvalidCurrency
+		}
+		req.Currency = &currency
+	}
+
 	return u.shopRepo.UpdateProduct(req)
 }
 
+// dipakai saat create: harga tidak boleh negatif, mata uang kosong diisi default
+func validatePrice(price int64, currency string) (string, error) {
+	if price < 0 {
+		return "", helper.ErrInvalidPrice
+	}
+
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		currency = helper.DefaultCurrency
+	}
+	if !helper.IsValidCurrency(currency) {
+		return "", helper.ErrInvalidCurrency
+	}
+
+	return currency, nil
+}
+
 func (u *shopUsecase) DeleteProduct(userId, storeId, id uint) error {
 	valid, err := u.shopRepo.IsUserAdminStore(userId, storeId)
 	if err != nil {
@@ -125,6 +190,10 @@ func (u *shopUsecase) GetMyCartItems(userId uint) ([]dto.CartItem, error) {
 	return u.shopRepo.GetMyCartItems(userId)
 }
 
+func (u *shopUsecase) GetMyCartTotal(userId uint) (*dto.CartTotal, error) {
+	return u.shopRepo.GetMyCartTotal(userId)
+}
+
 func (u *shopUsecase) CreateCartItem(req *dto.CreateCartItemReq) error {
 	valid, err := u.shopRepo.CheckStock(req.ProductID, req.PurchaseAmount)
 	if err != nil {
@@ -148,6 +217,10 @@ func (u *shopUsecase) UpdateAmountCartItem(req *dto.UpdateAmountCartItemReq) err
 }
 
 func (u *shopUsecase) UpdatePaidCartItem(req *dto.UpdatePaidCartItemReq) error {
+	if err := u.checkVerified(req.UserID); err != nil {
+		return err
+	}
+
 	valid, err := u.shopRepo.CheckStock(req.ProductID, req.PurchaseAmount)
 	if err != nil {
 		return err
@@ -161,3 +234,33 @@ func (u *shopUsecase) UpdatePaidCartItem(req *dto.UpdatePaidCartItemReq) error {
 func (u *shopUsecase) DeleteCartItem(userId, id uint) error {
 	return u.shopRepo.DeleteCartItem(userId, id)
 }
+
+// order
+func (u *shopUsecase) Checkout(req *dto.CheckoutReq) (*dto.Order, error) {
+	if err := u.checkVerified(req.UserID); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uint]bool)
+	var ids []uint
+	for _, id := range req.CartItemIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, helper.ErrEmptyCheckout
+	}
+
+	req.CartItemIDs = ids
+	return u.shopRepo.Checkout(req)
+}
+
+func (u *shopUsecase) GetMyOrders(userId uint) ([]dto.Order, error) {
+	return u.shopRepo.GetMyOrders(userId)
+}
+
+func (u *shopUsecase) GetMyOrder(userId, id uint) (*dto.Order, error) {
+	return u.shopRepo.GetMyOrder(userId, id)
+}
@@ -0,0 +1,159 @@
+package worker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Spec menghitung waktu jalan berikutnya sebuah jadwal
+type Spec interface {
+	Next(after time.Time) time.Time
+}
+
+// ParseSpec menerima format cron 5 field (menit jam tanggal bulan hari),
+// "@every <durasi>", "@hourly", "@daily" / "@midnight" dan "@weekly"
+func ParseSpec(spec string) (Spec, error) {
+	spec = strings.TrimSpace(spec)
+
+	switch spec {
+	case "@hourly":
+		spec = "0 * * * *"
+	case "@daily", "@midnight":
+		spec = "0 0 * * *"
+	case "@weekly":
+		spec = "0 0 * * 0"
+	}
+
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || d < time.Second {
+			return nil, fmt.Errorf("cron: durasi %q tidak valid", rest)
+		}
+		return everySpec(d), nil
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron: %q harus punya 5 field", spec)
+	}
+
+	// hari 0 dan 7 sama-sama minggu
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	var c cronSpec
+	sets := []*uint64{&c.minute, &c.hour, &c.dom, &c.month, &c.dow}
+	for i, f := range fields {
+		set, err := parseField(f, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("cron: field %q: %v", f, err)
+		}
+		*sets[i] = set
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow = c.dow&^(1<<7) | 1
+	}
+	// seperti cron biasa, field yang diawali "*" (termasuk "*/n") dianggap tidak membatasi
+	c.domStar = strings.HasPrefix(fields[2], "*")
+	c.dowStar = strings.HasPrefix(fields[4], "*")
+
+	return c, nil
+}
+
+// parseField mendukung "*", "*/n", "a", "a-b", "a-b/n" dan daftar dipisah koma
+func parseField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		step, stepped := 1, false
+		if base, s, ok := strings.Cut(part, "/"); ok {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("step %q tidak valid", s)
+			}
+			step, stepped = n, true
+			part = base
+		}
+
+		lo, hi := min, max
+		if part != "*" {
+			a, b, isRange := strings.Cut(part, "-")
+			var err error
+			if lo, err = strconv.Atoi(a); err != nil {
+				return 0, fmt.Errorf("angka %q tidak valid", a)
+			}
+			hi = lo
+			switch {
+			case isRange:
+				if hi, err = strconv.Atoi(b); err != nil {
+					return 0, fmt.Errorf("angka %q tidak valid", b)
+				}
+			case stepped:
+				// "a/n" berarti mulai dari a sampai batas atas
+				hi = max
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("di luar rentang %d-%d", min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+
+	return set, nil
+}
+
+type cronSpec struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+func (c cronSpec) dayMatches(t time.Time) bool {
+	domOk := c.dom&(1<<uint(t.Day())) != 0
+	dowOk := c.dow&(1<<uint(t.Weekday())) != 0
+
+	// seperti cron biasa: jika tanggal dan hari sama-sama dibatasi, cukup salah satu cocok
+	if !c.domStar && !c.dowStar {
+		return domOk || dowOk
+	}
+	return domOk && dowOk
+}
+
+// Next dihitung lewat time.Date di zona after. Truncate bekerja dari waktu absolut,
+// jadi salah untuk zona yang offset-nya bukan jam penuh (mis. +05:30)
+func (c cronSpec) Next(after time.Time) time.Time {
+	loc := after.Location()
+	t := time.Date(after.Year(), after.Month(), after.Day(), after.Hour(), after.Minute()+1, 0, 0, loc)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+// everySpec diselaraskan ke epoch supaya semua instance menghitung waktu yang sama
+type everySpec time.Duration
+
+func (e everySpec) Next(after time.Time) time.Time {
+	d := time.Duration(e)
+	return after.Truncate(d).Add(d)
+}
@@ -0,0 +1,92 @@
+package worker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSpecInvalid(t *testing.T) {
+	specs := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1-x * * * *",
+		"@every 0s",
+		"@every 500ms",
+		"@every nanti",
+	}
+
+	for _, spec := range specs {
+		if _, err := ParseSpec(spec); err == nil {
+			t.Errorf("ParseSpec(%q) seharusnya gagal", spec)
+		}
+	}
+}
+
+func TestSpecNext(t *testing.T) {
+	ist := time.FixedZone("IST", 5*3600+30*60)
+	// minggu, 18 oktober 2026
+	after := time.Date(2026, 10, 18, 10, 40, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec  string
+		after time.Time
+		want  time.Time
+	}{
+		{"* * * * *", after, time.Date(2026, 10, 18, 10, 41, 0, 0, time.UTC)},
+		{"*/15 * * * *", after, time.Date(2026, 10, 18, 10, 45, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 10, 18, 10, 45, 0, 0, time.UTC), time.Date(2026, 10, 18, 11, 0, 0, 0, time.UTC)},
+		{"5/10 * * * *", after, time.Date(2026, 10, 18, 10, 45, 0, 0, time.UTC)},
+		{"5/10 * * * *", time.Date(2026, 10, 18, 10, 56, 0, 0, time.UTC), time.Date(2026, 10, 18, 11, 5, 0, 0, time.UTC)},
+		{"10-20/5 * * * *", after, time.Date(2026, 10, 18, 11, 10, 0, 0, time.UTC)},
+		{"0,30 * * * *", after, time.Date(2026, 10, 18, 11, 0, 0, 0, time.UTC)},
+		{"@hourly", after, time.Date(2026, 10, 18, 11, 0, 0, 0, time.UTC)},
+		{"30 2 * * *", after, time.Date(2026, 10, 19, 2, 30, 0, 0, time.UTC)},
+		{"@daily", after, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", after, time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", after, time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 5-7", after, time.Date(2026, 10, 23, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", after, time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", after, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 1 *", after, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// tanggal dan hari sama-sama dibatasi: cukup salah satu cocok
+		{"0 0 13 * 5", after, time.Date(2026, 10, 23, 0, 0, 0, 0, time.UTC)},
+		// "*/n" tidak dihitung membatasi, jadi tanggal ganjil dan jumat harus cocok dua-duanya
+		{"0 0 */2 * 5", after, time.Date(2026, 10, 23, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", after, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// offset bukan jam penuh
+		{"0 * * * *", time.Date(2026, 10, 18, 10, 40, 0, 0, ist), time.Date(2026, 10, 18, 11, 0, 0, 0, ist)},
+		{"0 9 * * *", time.Date(2026, 10, 18, 10, 40, 0, 0, ist), time.Date(2026, 10, 19, 9, 0, 0, 0, ist)},
+		{"@every 10m", after, time.Date(2026, 10, 18, 10, 50, 0, 0, time.UTC)},
+		{"@every 1h", after, time.Date(2026, 10, 18, 11, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		spec, err := ParseSpec(tt.spec)
+		if err != nil {
+			t.Errorf("ParseSpec(%q): %v", tt.spec, err)
+			continue
+		}
+		if got := spec.Next(tt.after); !got.Equal(tt.want) {
+			t.Errorf("%q.Next(%s) = %s, ingin %s", tt.spec, tt.after, got, tt.want)
+		}
+	}
+}
+
+func TestSpecNextNever(t *testing.T) {
+	spec, err := ParseSpec("0 0 31 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := spec.Next(time.Now()); !got.IsZero() {
+		t.Errorf("31 februari seharusnya tidak pernah jalan, dapat %s", got)
+	}
+}
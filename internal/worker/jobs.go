@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"api_shope/internal/queue"
+	"api_shope/utils/mailer"
+	"context"
+	"time"
+)
+
+var mailOptions = HandlerOptions{
+	Timeout: 30 * time.Second,
+}
+
+// RegisterMailJobs mendaftarkan job yang berujung kirim email, isi email dari template
+func RegisterMailJobs(r *Registry, m mailer.Mailer, renderer *mailer.Renderer) {
+	Register(r, "register", mailOptions, sendMail(m, renderer, "register", "welcome"))
+	Register(r, "buy", mailOptions, sendMail(m, renderer, "buy product", "receipt"))
+	Register(r, "verify", mailOptions, sendMail(m, renderer, "verify email", "verify"))
+	Register(r, "reset", mailOptions, sendMail(m, renderer, "reset password", "reset"))
+	Register(r, "cart_reminder", mailOptions, sendMail(m, renderer, "cart reminder", "cart_reminder"))
+}
+
+func sendMail(m mailer.Mailer, renderer *mailer.Renderer, label, template string) func(ctx context.Context, payload queue.MailPayload) error {
+	return func(ctx context.Context, payload queue.MailPayload) error {
+		// job lama tanpa data template dikirim apa adanya
+		if payload.Data == nil && payload.Message != "" {
+			return m.Send(ctx, mailer.Message{
+				To:      payload.Email,
+				Subject: label,
+				Text:    payload.Message,
+			})
+		}
+
+		rendered, err := renderer.Render(template, payload.Locale, payload.Data)
+		if err != nil {
+			return Permanent(err)
+		}
+
+		return m.Send(ctx, mailer.Message{
+			To:      payload.Email,
+			Subject: rendered.Subject,
+			HTML:    rendered.HTML,
+			Text:    rendered.Text,
+		})
+	}
+}
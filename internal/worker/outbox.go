@@ -0,0 +1,94 @@
+package worker
+
+import (
+	"api_shope/internal/queue"
+	"api_shope/model"
+	"context"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	relayInterval = time.Second
+	relayBatch    = 100
+
+	// dedupe key dan baris outbox yang sudah terkirim disimpan selama ini
+	outboxRetention = 7 * 24 * time.Hour
+	cleanupInterval = time.Hour
+)
+
+// relayLoop memindahkan baris outbox yang belum terkirim ke stream
+func (w *Worker) relayLoop(stopCtx context.Context) {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(relayInterval)
+	defer ticker.Stop()
+	lastCleanup := time.Now()
+
+	for {
+		select {
+		case <-stopCtx.Done():
+			return
+		case <-ticker.C:
+			if err := w.relayOutbox(); err != nil {
+				log.Println("worker: gagal relay outbox:", err)
+			}
+
+			if time.Since(lastCleanup) >= cleanupInterval {
+				lastCleanup = time.Now()
+				w.cleanupOutbox()
+			}
+		}
+	}
+}
+
+// relayOutbox mengunci batch outbox (SKIP LOCKED supaya replika lain mengambil batch berbeda),
+// publish ke stream lewat dedupe key, lalu menandai terkirim. jika proses mati setelah publish
+// tapi sebelum commit, dedupe key mencegah job yang sama masuk dua kali.
+func (w *Worker) relayOutbox() error {
+	return w.DB.Transaction(func(tx *gorm.DB) error {
+		var rows []model.Outbox
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("sent_at IS NULL").
+			Order("id").
+			Limit(relayBatch).
+			Find(&rows).Error; err != nil {
+			return err
+		}
+
+		if len(rows) == 0 {
+			return nil
+		}
+
+		sent := make([]uint, 0, len(rows))
+		for _, row := range rows {
+			job := &queue.Job{
+				ID:      row.JobID,
+				Op:      row.Op,
+				Payload: row.Payload,
+			}
+
+			if _, err := queue.PushOnce(ctx, w.Redis, job, "behind:outbox:"+job.ID, outboxRetention); err != nil {
+				log.Printf("worker: gagal publish outbox %d: %v", row.ID, err)
+				break
+			}
+			sent = append(sent, row.ID)
+		}
+
+		if len(sent) == 0 {
+			return nil
+		}
+
+		return tx.Model(&model.Outbox{}).Where("id IN ?", sent).Update("sent_at", time.Now()).Error
+	})
+}
+
+func (w *Worker) cleanupOutbox() {
+	before := time.Now().Add(-outboxRetention)
+	if err := w.DB.Where("sent_at IS NOT NULL AND sent_at < ?", before).Delete(&model.Outbox{}).Error; err != nil {
+		log.Println("worker: gagal membersihkan outbox:", err)
+	}
+}
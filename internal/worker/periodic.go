@@ -0,0 +1,99 @@
+package worker
+
+import (
+	"api_shope/internal/queue"
+	"api_shope/internal/repository"
+	"api_shope/model"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	// cart yang belum dibayar selama rentang ini dianggap ditinggalkan,
+	// jadwal harian membuat setiap cart hanya diingatkan sekali
+	abandonedAfter  = 24 * time.Hour
+	abandonedWindow = 24 * time.Hour
+
+	reminderKey = "behind:cart_reminder:"
+	reminderTTL = 48 * time.Hour
+)
+
+var periodicOptions = HandlerOptions{
+	Timeout: 5 * time.Minute,
+	Retry:   RetryPolicy{MaxAttempts: 3},
+}
+
+// RegisterPeriodicJobs mendaftarkan job yang dipicu scheduler. warmup memakai batas beban
+// yang sama dengan warmup saat boot dan cmd/warmcache.
+func RegisterPeriodicJobs(r *Registry, db *gorm.DB, rdb *redis.Client, shopRepo repository.ShopRepo, warmup repository.WarmupOptions) {
+	Register(r, "cache_warmup", periodicOptions, func(ctx context.Context, _ struct{}) error {
+		return shopRepo.WarmCache(warmup)
+	})
+	Register(r, "abandoned_cart", periodicOptions, func(ctx context.Context, _ struct{}) error {
+		return remindAbandonedCarts(ctx, db, rdb, time.Now())
+	})
+}
+
+// DefaultSchedules adalah jadwal bawaan, waktu mengikuti zona waktu server
+func DefaultSchedules(s *Scheduler) error {
+	if err := s.Add("nightly-cache-warmup", "0 2 * * *", "cache_warmup", struct{}{}); err != nil {
+		return err
+	}
+	if err := s.Add("abandoned-cart-reminder", "0 9 * * *", "abandoned_cart", struct{}{}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type abandonedCart struct {
+	UserID    uint
+	ItemCount int
+}
+
+// remindAbandonedCarts mengirim email ke user yang punya cart belum dibayar.
+// dedupe key per user per hari mencegah email ganda jika job di-retry.
+func remindAbandonedCarts(ctx context.Context, db *gorm.DB, rdb *redis.Client, now time.Time) error {
+	until := now.Add(-abandonedAfter)
+	since := until.Add(-abandonedWindow)
+
+	var carts []abandonedCart
+	if err := db.WithContext(ctx).Model(&model.CartItem{}).
+		Select("user_id, COUNT(*) AS item_count").
+		Where("is_paid = ? AND is_product_deleted = ? AND created_at >= ? AND created_at < ?", false, false, since, until).
+		Group("user_id").
+		Scan(&carts).Error; err != nil {
+		return err
+	}
+
+	for _, cart := range carts {
+		var user model.User
+		if err := db.WithContext(ctx).Select("id", "username", "email", "locale").First(&user, cart.UserID).Error; err != nil {
+			return err
+		}
+
+		job, err := queue.NewJob("cart_reminder", queue.MailPayload{
+			ID:     user.ID,
+			Email:  user.Email,
+			Locale: user.Locale,
+			Data: map[string]interface{}{
+				"Name":      user.Username,
+				"ItemCount": cart.ItemCount,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		dedupeKey := fmt.Sprintf("%s%d:%s", reminderKey, user.ID, now.Format("2006-01-02"))
+		if _, err := queue.PushOnce(ctx, rdb, job, dedupeKey, reminderTTL); err != nil {
+			return fmt.Errorf("redis: %v", err)
+		}
+	}
+
+	return nil
+}
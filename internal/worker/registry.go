@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RetryPolicy per job type, nilai nol berarti memakai default dari Worker
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+type HandlerOptions struct {
+	// batas waktu satu kali eksekusi handler, nol berarti default worker
+	Timeout time.Duration
+	Retry   RetryPolicy
+}
+
+type jobHandler struct {
+	opts HandlerOptions
+	run  func(ctx context.Context, payload string) error
+}
+
+// Registry memetakan job type ke handler beserta payload-nya masing-masing
+type Registry struct {
+	mu       sync.RWMutex
+	handlers map[string]jobHandler
+}
+
+func NewRegistry() *Registry {
+	return &Registry{handlers: make(map[string]jobHandler)}
+}
+
+// Register mendaftarkan handler untuk job type op. payload job di-decode ke T
+// sebelum fn dipanggil, payload yang tidak valid langsung masuk dead letter.
+func Register[T any](r *Registry, op string, opts HandlerOptions, fn func(ctx context.Context, payload T) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.handlers[op] = jobHandler{
+		opts: opts,
+		run: func(ctx context.Context, raw string) error {
+			var payload T
+			if err := json.Unmarshal([]byte(raw), &payload); err != nil {
+				return Permanent(fmt.Errorf("payload tidak valid: %v", err))
+			}
+			return fn(ctx, payload)
+		},
+	}
+}
+
+func (r *Registry) lookup(op string) (jobHandler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	h, ok := r.handlers[op]
+	return h, ok
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent menandai error yang tidak perlu di-retry, job langsung masuk dead letter
+func Permanent(err error) error {
+	return &permanentError{err}
+}
+
+func isPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
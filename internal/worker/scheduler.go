@@ -0,0 +1,184 @@
+package worker
+
+import (
+	"api_shope/internal/queue"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// hanya instance yang memegang lock ini yang menjalankan jadwal
+	leaderKey = "behind:scheduler:leader"
+	leaderTTL = 15 * time.Second
+
+	scheduleInterval = time.Second
+
+	// dedupe per eksekusi, supaya pergantian leader tidak menjalankan jadwal yang sama dua kali
+	firedKey = "behind:scheduler:fired:"
+	firedTTL = 24 * time.Hour
+)
+
+// renewLeaderScript memperpanjang lock hanya jika masih dipegang instance ini
+var renewLeaderScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+var releaseLeaderScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+type Schedule struct {
+	Name    string
+	Spec    string
+	Op      string
+	Payload interface{}
+
+	spec Spec
+	next time.Time
+}
+
+// Scheduler memasukkan job berulang ke stream sesuai jadwal cron.
+// semua instance menghitung jadwal, tapi hanya leader yang benar-benar enqueue.
+type Scheduler struct {
+	redis *redis.Client
+	id    string
+
+	mu        sync.Mutex
+	schedules []*Schedule
+	leader    bool
+}
+
+func NewScheduler(redis *redis.Client, id string) *Scheduler {
+	return &Scheduler{redis: redis, id: id}
+}
+
+// Add mendaftarkan job berulang, name harus unik karena dipakai sebagai dedupe key
+func (s *Scheduler) Add(name, spec, op string, payload interface{}) error {
+	parsed, err := ParseSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, sc := range s.schedules {
+		if sc.Name == name {
+			return fmt.Errorf("scheduler: jadwal %q sudah terdaftar", name)
+		}
+	}
+
+	s.schedules = append(s.schedules, &Schedule{
+		Name:    name,
+		Spec:    spec,
+		Op:      op,
+		Payload: payload,
+		spec:    parsed,
+		next:    parsed.Next(time.Now()),
+	})
+
+	return nil
+}
+
+// elect mencoba menjadi leader atau memperpanjang lock yang sudah dipegang
+func (s *Scheduler) elect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.leader {
+		renewed, err := renewLeaderScript.Run(ctx, s.redis, []string{leaderKey}, s.id, leaderTTL.Milliseconds()).Int()
+		if err != nil || renewed == 0 {
+			log.Println("scheduler: kehilangan leader lock")
+			s.leader = false
+		}
+		return
+	}
+
+	ok, err := s.redis.SetNX(ctx, leaderKey, s.id, leaderTTL).Result()
+	if err != nil {
+		log.Println("scheduler: gagal mengambil leader lock:", err)
+		return
+	}
+	if ok {
+		log.Printf("scheduler: %s menjadi leader", s.id)
+		s.leader = true
+	}
+}
+
+func (s *Scheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.leader {
+		return
+	}
+	if err := releaseLeaderScript.Run(ctx, s.redis, []string{leaderKey}, s.id).Err(); err != nil {
+		log.Println("scheduler: gagal melepas leader lock:", err)
+	}
+	s.leader = false
+}
+
+// tick menjalankan jadwal yang sudah jatuh tempo. non-leader tetap memajukan jadwal
+// supaya saat mengambil alih tidak menjalankan jadwal yang sudah lewat.
+func (s *Scheduler) tick(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, sc := range s.schedules {
+		if now.Before(sc.next) {
+			continue
+		}
+
+		if s.leader {
+			s.fire(sc)
+		}
+		sc.next = sc.spec.Next(now)
+	}
+}
+
+func (s *Scheduler) fire(sc *Schedule) {
+	job, err := queue.NewJob(sc.Op, sc.Payload)
+	if err != nil {
+		log.Printf("scheduler: jadwal %s tidak valid: %v", sc.Name, err)
+		return
+	}
+
+	dedupeKey := fmt.Sprintf("%s%s:%d", firedKey, sc.Name, sc.next.Unix())
+	added, err := queue.PushOnce(ctx, s.redis, job, dedupeKey, firedTTL)
+	if err != nil {
+		log.Printf("scheduler: gagal enqueue jadwal %s: %v", sc.Name, err)
+		return
+	}
+	if added {
+		log.Printf("scheduler: jadwal %s masuk queue sebagai job %s", sc.Name, job.ID)
+	}
+}
+
+func (w *Worker) scheduleLoop(stopCtx context.Context) {
+	defer w.wg.Done()
+	defer w.Scheduler.release()
+
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCtx.Done():
+			return
+		case now := <-ticker.C:
+			w.Scheduler.elect()
+			w.Scheduler.tick(now)
+		}
+	}
+}
@@ -1,9 +1,16 @@
 package worker
 
 import (
-	"api_shope/utils/helper"
+	"api_shope/internal/queue"
+	"api_shope/internal/repository"
+	"api_shope/utils/mailer"
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,77 +20,413 @@ import (
 
 var ctx = context.Background()
 
+// errAborted berarti handler dibatalkan karena shutdown, bukan karena job gagal.
+// job seperti ini tidak di-ack supaya diambil alih worker lain lewat reclaim
+var errAborted = errors.New("dibatalkan karena shutdown")
+
+const (
+	// berapa lama XREADGROUP menunggu job baru sebelum loop dicek ulang
+	readBlock = 2 * time.Second
+
+	// jumlah job yang diproses bersamaan jika Concurrency tidak diisi
+	defaultConcurrency = 4
+
+	// job yang tidak di-ack selama ini dianggap milik consumer yang mati
+	reclaimIdle     = time.Minute
+	reclaimInterval = 30 * time.Second
+
+	// seberapa sering job di sorted set delayed dicek
+	promoteInterval = time.Second
+	promoteBatch    = 100
+
+	// seberapa sering entry stream yang sudah di-ack dibuang
+	trimInterval = time.Minute
+)
+
 type Worker struct {
-	DB      *gorm.DB
-	Redis   *redis.Client
-	ticker  *time.Ticker
-	quit    chan struct{}
+	DB       *gorm.DB
+	Redis    *redis.Client
+	Consumer string
+
+	Registry  *Registry
+	Scheduler *Scheduler
+
+	// default retry dengan exponential backoff + jitter, setelah MaxAttempts job masuk dead letter.
+	// handler bisa menimpa lewat HandlerOptions.Retry
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	Timeout     time.Duration
+
+	// jumlah goroutine yang memproses job bersamaan
+	Concurrency int
+
+	// loop membaca/reclaim mengirim job ke processor lewat channel ini
+	jobs    chan redis.XMessage
+	cancel  context.CancelFunc
+	abort   context.CancelFunc
+	runCtx  context.Context
+	wg      sync.WaitGroup
+	procWg  sync.WaitGroup
 	running bool
 	mu      sync.Mutex
 }
 
-func NewWorker(db *gorm.DB, redis *redis.Client) *Worker {
+func NewWorker(db *gorm.DB, redis *redis.Client, m mailer.Mailer, renderer *mailer.Renderer, warmup repository.WarmupOptions) *Worker {
+	host, _ := os.Hostname()
+	consumer := fmt.Sprintf("%s-%d", host, os.Getpid())
+
+	// L1 berukuran 0 hanya dipakai untuk menyiarkan invalidasi ke instance api
+	shopRepo := repository.NewShopRepo(db, redis, repository.NewLocalCache(redis, 0, 0))
+
+	registry := NewRegistry()
+	RegisterMailJobs(registry, m, renderer)
+	RegisterPeriodicJobs(registry, db, redis, shopRepo, warmup)
+
+	scheduler := NewScheduler(redis, consumer)
+	if err := DefaultSchedules(scheduler); err != nil {
+		log.Println("worker: jadwal bawaan tidak valid:", err)
+	}
+
 	return &Worker{
-		DB:    db,
-		Redis: redis,
+		DB:        db,
+		Redis:     redis,
+		Consumer:  consumer,
+		Registry:  registry,
+		Scheduler: scheduler,
+
+		MaxAttempts: 5,
+		BaseBackoff: 5 * time.Second,
+		MaxBackoff:  10 * time.Minute,
+		Timeout:     time.Minute,
+		Concurrency: defaultConcurrency,
 	}
 }
 
-func (w *Worker) flushPendingItems() {
-	keys, _ := w.Redis.Keys(ctx, "behind:pending:*").Result()
-	for _, key := range keys {
-		data, _ := w.Redis.HGetAll(ctx, key).Result()
-		if len(data) == 0 {
+// ensureGroup membuat stream dan consumer group jika belum ada
+func (w *Worker) ensureGroup() error {
+	err := w.Redis.XGroupCreateMkStream(ctx, queue.StreamKey, queue.GroupName, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+
+	return nil
+}
+
+// job yang sedang diproses tetap memakai ctx biasa supaya selesai dan di-ack
+// walau worker sedang dihentikan, stopCtx hanya menghentikan pembacaan baru.
+// job yang sudah terbaca tetap dikirim ke processor supaya tidak menggantung di pending.
+func (w *Worker) readLoop(stopCtx context.Context) {
+	defer w.wg.Done()
+
+	for stopCtx.Err() == nil {
+		streams, err := w.Redis.XReadGroup(stopCtx, &redis.XReadGroupArgs{
+			Group:    queue.GroupName,
+			Consumer: w.Consumer,
+			Streams:  []string{queue.StreamKey, ">"},
+			Count:    int64(w.Concurrency),
+			Block:    readBlock,
+		}).Result()
+		if err != nil {
+			if !errors.Is(err, redis.Nil) && stopCtx.Err() == nil {
+				log.Println("worker: gagal membaca stream:", err)
+				time.Sleep(time.Second)
+			}
 			continue
 		}
 
-		op := data["op"]
-		switch op {
-		case "register":
-			fmt.Printf("%v masuk ke queue redis register", data["username"])
-			helper.SendEmail(data["email"], data["message"])
-		case "buy":
-			fmt.Printf("%v masuk ke queue redis buy product", data["username"])
-			helper.SendEmail(data["email"], data["message"])
+		for _, stream := range streams {
+			for _, msg := range stream.Messages {
+				w.jobs <- msg
+			}
+		}
+	}
+}
+
+// processLoop memproses job sampai channel ditutup saat shutdown
+func (w *Worker) processLoop() {
+	defer w.procWg.Done()
+
+	for msg := range w.jobs {
+		w.handleMessage(msg)
+	}
+}
+
+// reclaimLoop mengambil alih job yang terlalu lama pending di consumer lain
+func (w *Worker) reclaimLoop(stopCtx context.Context) {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(reclaimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCtx.Done():
+			return
+		case <-ticker.C:
+			w.reclaimPending(stopCtx)
+		}
+	}
+}
+
+func (w *Worker) reclaimPending(stopCtx context.Context) {
+	start := "0-0"
+	for stopCtx.Err() == nil {
+		msgs, next, err := w.Redis.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   queue.StreamKey,
+			Group:    queue.GroupName,
+			Consumer: w.Consumer,
+			MinIdle:  reclaimIdle,
+			Start:    start,
+			Count:    int64(w.Concurrency),
+		}).Result()
+		if err != nil {
+			log.Println("worker: gagal reclaim job:", err)
+			return
+		}
+
+		for _, msg := range msgs {
+			w.jobs <- msg
+		}
+
+		if next == "0-0" || len(msgs) == 0 {
+			return
+		}
+		start = next
+	}
+}
+
+// promoteLoop memindahkan job terjadwal dan job retry yang sudah jatuh tempo ke stream
+func (w *Worker) promoteLoop(stopCtx context.Context) {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(promoteInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCtx.Done():
+			return
+		case <-ticker.C:
+			if _, err := queue.PromoteDue(ctx, w.Redis, time.Now(), promoteBatch); err != nil {
+				log.Println("worker: gagal memindahkan job delayed:", err)
+			}
+		}
+	}
+}
+
+// trimLoop menjaga panjang stream tanpa membuang job yang belum selesai
+func (w *Worker) trimLoop(stopCtx context.Context) {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(trimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCtx.Done():
+			return
+		case <-ticker.C:
+			if _, err := queue.Trim(ctx, w.Redis); err != nil {
+				log.Println("worker: gagal memangkas stream:", err)
+			}
+		}
+	}
+}
+
+func (w *Worker) handleMessage(msg redis.XMessage) {
+	job := queue.ParseMessage(msg)
+	attempt := queue.AttemptLog{
+		Attempt:   job.Attempt + 1,
+		Consumer:  w.Consumer,
+		StartedAt: time.Now(),
+	}
+
+	if err := queue.SetStatus(ctx, w.Redis, job, queue.StatusRunning, "", time.Time{}); err != nil {
+		log.Printf("worker: gagal menyimpan status job %s: %v", job.ID, err)
+	}
+
+	h, ok := w.Registry.lookup(job.Op)
+	var err error
+	if !ok {
+		err = Permanent(fmt.Errorf("job type %q tidak terdaftar", job.Op))
+	} else {
+		err = w.process(h, job)
+	}
+	if errors.Is(err, errAborted) {
+		log.Printf("worker: job %s (%s) dibatalkan, menunggu reclaim", job.ID, job.Op)
+		return
+	}
+	attempt.FinishedAt = time.Now()
+
+	_, pipeErr := w.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if err != nil {
+			attempt.Error = err.Error()
+			status, err := w.retryOrDead(pipe, h.opts.Retry, job, err)
+			if err != nil {
+				return err
+			}
+			attempt.Status = status
+		} else {
+			attempt.Status = queue.StatusSucceeded
+			job.Attempt++
+			if err := queue.SetStatus(ctx, pipe, job, queue.StatusSucceeded, "", time.Time{}); err != nil {
+				return err
+			}
 		}
+		if err := queue.AddAttempt(ctx, pipe, job.ID, attempt); err != nil {
+			return err
+		}
+		pipe.XAck(ctx, queue.StreamKey, queue.GroupName, msg.ID)
+		return nil
+	})
+	if pipeErr != nil {
+		log.Printf("worker: gagal ack job %s: %v", job.ID, pipeErr)
+	}
+}
+
+// process menjalankan handler dengan batas waktu. handler yang tidak menghormati ctx
+// tetap dibiarkan selesai di background, tapi job dianggap gagal karena timeout.
+func (w *Worker) process(h jobHandler, job *queue.Job) error {
+	timeout := h.opts.Timeout
+	if timeout <= 0 {
+		timeout = w.Timeout
+	}
+
+	runCtx, cancel := context.WithTimeout(w.runCtx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- h.run(runCtx, job.Payload)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil && w.runCtx.Err() != nil {
+			return errAborted
+		}
+		return err
+	case <-runCtx.Done():
+		if w.runCtx.Err() != nil {
+			return errAborted
+		}
+		return fmt.Errorf("timeout setelah %v", timeout)
+	}
+}
+
+// retryOrDead menjadwalkan ulang job yang gagal, atau memindahkannya ke dead letter.
+// mengembalikan status akhir job (failed atau dead)
+func (w *Worker) retryOrDead(pipe redis.Pipeliner, policy RetryPolicy, job *queue.Job, cause error) (string, error) {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = w.MaxAttempts
+	}
+	if policy.BaseBackoff <= 0 {
+		policy.BaseBackoff = w.BaseBackoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = w.MaxBackoff
+	}
+
+	job.Attempt++
+	if isPermanent(cause) || job.Attempt >= policy.MaxAttempts {
+		log.Printf("worker: job %s (%s) gagal permanen: %v", job.ID, job.Op, cause)
+		return queue.StatusDead, queue.AddDead(ctx, pipe, job, cause.Error())
+	}
 
-		w.Redis.Del(ctx, key)
+	delay := backoff(job.Attempt, policy.BaseBackoff, policy.MaxBackoff)
+	runAt := time.Now().Add(delay)
+	log.Printf("worker: job %s (%s) gagal, retry ke-%d dalam %v: %v", job.ID, job.Op, job.Attempt, delay, cause)
+	if err := queue.Delay(ctx, pipe, job, runAt); err != nil {
+		return "", err
 	}
+
+	return queue.StatusFailed, queue.SetStatus(ctx, pipe, job, queue.StatusFailed, cause.Error(), runAt)
 }
 
-func (w *Worker) StartFlushWorker(interval time.Duration) {
+// backoff menghitung base*2^(attempt-1) dibatasi max, lalu diacak di rentang [d/2, d]
+func backoff(attempt int, base, max time.Duration) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+
+	half := d / 2
+	return half + time.Duration(rand.Int64N(int64(half)+1))
+}
+
+func (w *Worker) Start() error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
 	if w.running {
-		return
+		return nil
 	}
 
-	w.ticker = time.NewTicker(interval)
-	w.quit = make(chan struct{})
+	if err := w.ensureGroup(); err != nil {
+		return fmt.Errorf("worker: %v", err)
+	}
+	if w.Concurrency <= 0 {
+		w.Concurrency = defaultConcurrency
+	}
+
+	var stopCtx context.Context
+	stopCtx, w.cancel = context.WithCancel(ctx)
+	w.runCtx, w.abort = context.WithCancel(ctx)
+	w.jobs = make(chan redis.XMessage)
 	w.running = true
 
-	go func() {
-		for {
-			select {
-			case <-w.ticker.C:
-				w.flushPendingItems()
-			case <-w.quit:
-				w.ticker.Stop()
-				return
-			}
-		}
-	}()
+	w.procWg.Add(w.Concurrency)
+	for i := 0; i < w.Concurrency; i++ {
+		go w.processLoop()
+	}
+
+	w.wg.Add(6)
+	go w.readLoop(stopCtx)
+	go w.reclaimLoop(stopCtx)
+	go w.promoteLoop(stopCtx)
+	go w.trimLoop(stopCtx)
+	go w.relayLoop(stopCtx)
+	go w.scheduleLoop(stopCtx)
+
+	return nil
 }
 
-func (w *Worker) StopFlushWorker() {
+// Shutdown berhenti membaca job baru lalu menunggu job yang sedang diproses selesai.
+// jika shutdownCtx habis duluan, handler yang masih berjalan dibatalkan dan job
+// yang belum di-ack nanti diambil alih worker lain lewat reclaim.
+func (w *Worker) Shutdown(shutdownCtx context.Context) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
 	if !w.running {
-		return
+		return nil
 	}
-
-	close(w.quit)
 	w.running = false
+	w.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(w.jobs)
+		w.procWg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		w.abort()
+		return nil
+	case <-shutdownCtx.Done():
+		w.abort()
+		return fmt.Errorf("worker: %v", shutdownCtx.Err())
+	}
+}
+
+// Stop menghentikan loop dan menunggu job yang sedang diproses selesai tanpa batas waktu
+func (w *Worker) Stop() {
+	w.Shutdown(ctx)
 }
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS `outboxes`;
+DROP TABLE IF EXISTS `order_lines`;
+DROP TABLE IF EXISTS `orders`;
+DROP TABLE IF EXISTS `cart_items`;
+DROP TABLE IF EXISTS `products`;
+DROP TABLE IF EXISTS `stores`;
+DROP TABLE IF EXISTS `users`;
@@ -0,0 +1,93 @@
+-- skema awal, sama dengan hasil AutoMigrate sebelumnya. IF NOT EXISTS membuat
+-- database lama yang dibuat AutoMigrate bisa langsung memakai migrasi ini.
+
+CREATE TABLE IF NOT EXISTS `users` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `username` varchar(191) NOT NULL,
+  `email` varchar(191) NOT NULL,
+  `password` longtext NOT NULL,
+  `verified` boolean DEFAULT false,
+  `locale` varchar(5) NOT NULL DEFAULT 'id',
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `uni_users_username` (`username`),
+  UNIQUE INDEX `uni_users_email` (`email`)
+);
+
+CREATE TABLE IF NOT EXISTS `stores` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `name` varchar(191) NOT NULL,
+  `admin_id` bigint unsigned,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `uni_stores_name` (`name`),
+  UNIQUE INDEX `uni_stores_admin_id` (`admin_id`),
+  INDEX `idx_stores_admin_id` (`admin_id`),
+  CONSTRAINT `fk_users_store` FOREIGN KEY (`admin_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS `products` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `name` longtext NOT NULL,
+  `stock` bigint NOT NULL,
+  `price` bigint NOT NULL DEFAULT 0,
+  `currency` varchar(3) NOT NULL DEFAULT 'IDR',
+  `store_id` bigint unsigned,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_products_store_id` (`store_id`),
+  CONSTRAINT `fk_stores_product` FOREIGN KEY (`store_id`) REFERENCES `stores` (`id`) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS `cart_items` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `purchase_amount` bigint NOT NULL,
+  `is_paid` boolean DEFAULT false,
+  `created_at` datetime(3) NULL,
+  `is_product_deleted` boolean DEFAULT false,
+  `user_id` bigint unsigned,
+  `product_id` bigint unsigned,
+  PRIMARY KEY (`id`),
+  INDEX `idx_cart_items_user_id` (`user_id`),
+  INDEX `idx_cart_items_product_id` (`product_id`),
+  CONSTRAINT `fk_cart_items_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_cart_items_product` FOREIGN KEY (`product_id`) REFERENCES `products` (`id`) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS `orders` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `status` longtext NOT NULL,
+  `total_item` bigint NOT NULL,
+  `total_price` bigint NOT NULL DEFAULT 0,
+  `currency` varchar(3) NOT NULL DEFAULT 'IDR',
+  `created_at` datetime(3) NULL,
+  `user_id` bigint unsigned,
+  PRIMARY KEY (`id`),
+  INDEX `idx_orders_user_id` (`user_id`),
+  CONSTRAINT `fk_orders_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS `order_lines` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `product_name` longtext NOT NULL,
+  `purchase_amount` bigint NOT NULL,
+  `unit_price` bigint NOT NULL DEFAULT 0,
+  `sub_total` bigint NOT NULL DEFAULT 0,
+  `created_at` datetime(3) NULL,
+  `order_id` bigint unsigned,
+  `product_id` bigint unsigned,
+  PRIMARY KEY (`id`),
+  INDEX `idx_order_lines_order_id` (`order_id`),
+  INDEX `idx_order_lines_product_id` (`product_id`),
+  CONSTRAINT `fk_orders_order_line` FOREIGN KEY (`order_id`) REFERENCES `orders` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_order_lines_product` FOREIGN KEY (`product_id`) REFERENCES `products` (`id`) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS `outboxes` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `op` longtext NOT NULL,
+  `payload` text NOT NULL,
+  `sent_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_outboxes_sent_at` (`sent_at`)
+);
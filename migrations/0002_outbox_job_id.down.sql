@@ -0,0 +1 @@
+ALTER TABLE `outboxes` DROP INDEX `idx_outboxes_job_id`, DROP COLUMN `job_id`;
@@ -0,0 +1,9 @@
+-- id job outbox dibuat acak saat baris ditulis. sebelumnya diturunkan dari id baris,
+-- jadi setelah TRUNCATE/rollback id yang terpakai ulang dianggap duplikat oleh dedupe
+-- key di redis. baris lama diisi format lama supaya dedupe-nya tetap berlaku.
+
+ALTER TABLE `outboxes` ADD COLUMN `job_id` varchar(64) NULL AFTER `id`;
+
+UPDATE `outboxes` SET `job_id` = CONCAT('outbox-', `id`) WHERE `job_id` IS NULL;
+
+ALTER TABLE `outboxes` MODIFY `job_id` varchar(64) NOT NULL, ADD UNIQUE INDEX `idx_outboxes_job_id` (`job_id`);
@@ -0,0 +1,8 @@
+// Package migrations berisi file migrasi sql bernomor, <versi>_<nama>.up.sql dan
+// <versi>_<nama>.down.sql. file ikut di-embed ke binary cmd/migrations.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -7,6 +7,8 @@ type User struct {
 	Username string `gorm:"unique;not null"`
 	Email    string `gorm:"unique;not null"`
 	Password string `gorm:"not null"`
+	Verified bool   `gorm:"default:false"`
+	Locale   string `gorm:"size:5;not null;default:'id'"`
 
 	//relasi
 	Store Store `gorm:"foreignKey:AdminID;constraint:OnDelete:CASCADE;"`
@@ -29,6 +31,10 @@ type Product struct {
 	Name  string `gorm:"not null"`
 	Stock int    `gorm:"not null"`
 
+	//harga dalam minor unit
+	Price    int64  `gorm:"not null;default:0"`
+	Currency string `gorm:"size:3;not null;default:'IDR'"`
+
 	//store
 	StoreID   uint `gorm:"index"`
 	CreatedAt time.Time
@@ -48,3 +54,52 @@ type CartItem struct {
 	ProductID *uint    `gorm:"index"`
 	Product   *Product `gorm:"foreignKey:ProductID;constraint:OnDelete:SET NULL;"`
 }
+
+const (
+	OrderStatusPaid = "paid"
+)
+
+type Order struct {
+	ID         uint   `gorm:"primaryKey"`
+	Status     string `gorm:"not null"`
+	TotalItem  int    `gorm:"not null"`
+	TotalPrice int64  `gorm:"not null;default:0"`
+	Currency   string `gorm:"size:3;not null;default:'IDR'"`
+	CreatedAt  time.Time
+
+	//user
+	UserID uint `gorm:"index"`
+	User   User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+
+	//line
+	OrderLine []OrderLine `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE;"`
+}
+
+type OrderLine struct {
+	ID             uint   `gorm:"primaryKey"`
+	ProductName    string `gorm:"not null"`
+	PurchaseAmount int    `gorm:"not null"`
+	UnitPrice      int64  `gorm:"not null;default:0"`
+	SubTotal       int64  `gorm:"not null;default:0"`
+	CreatedAt      time.Time
+
+	//order
+	OrderID uint `gorm:"index"`
+
+	//product
+	ProductID *uint    `gorm:"index"`
+	Product   *Product `gorm:"foreignKey:ProductID;constraint:OnDelete:SET NULL;"`
+}
+
+// Outbox ditulis dalam transaksi yang sama dengan perubahan data,
+// lalu dikirim ke queue oleh relay di worker. JobID dipakai sebagai id job dan
+// dedupe key relay, dibuat acak supaya tidak bentrok dengan id baris yang
+// terpakai ulang setelah tabel dikosongkan.
+type Outbox struct {
+	ID        uint       `gorm:"primaryKey"`
+	JobID     string     `gorm:"size:64;not null;uniqueIndex"`
+	Op        string     `gorm:"not null"`
+	Payload   string     `gorm:"type:text;not null"`
+	SentAt    *time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
@@ -7,9 +7,33 @@ var (
 
 	//auth
 	ErrInvalidEmail = errors.New("email tidak sesuai")
+	ErrInvalidToken = errors.New("token tidak valid")
+	ErrTokenReused  = errors.New("refresh token sudah pernah dipakai, semua sesi dicabut")
+	ErrTokenRevoked = errors.New("token sudah dicabut")
+
+	ErrEmailNotVerified = errors.New("email belum diverifikasi")
+	ErrAlreadyVerified  = errors.New("email sudah diverifikasi")
+	ErrTooManyRequest   = errors.New("terlalu sering, coba lagi nanti")
+
+	ErrWrongPassword = errors.New("password lama salah")
+	ErrWeakPassword  = errors.New("password minimal 8 karakter")
 
 	//shop
 	ErrNotAdmin       = errors.New("kau bukan admin")
 	ErrStocknotEnough = errors.New("stock tidak cukup")
 	ErrUnavaible      = errors.New("hasil memang tidak ada")
+
+	//price
+	ErrInvalidPrice    = errors.New("harga tidak valid")
+	ErrInvalidCurrency = errors.New("mata uang tidak didukung")
+	ErrMoneyOverflow   = errors.New("total harga terlalu besar")
+	ErrMixedCurrency   = errors.New("mata uang item berbeda-beda")
+	ErrPriceRequired   = errors.New("harga wajib diisi saat mengganti mata uang")
+
+	//order
+	ErrEmptyCheckout   = errors.New("tidak ada cart item yang dipilih")
+	ErrInvalidCartItem = errors.New("cart item tidak valid atau sudah dibayar")
+
+	//queue
+	ErrJobNotIdle = errors.New("job sedang antri, berjalan atau sudah selesai")
 )
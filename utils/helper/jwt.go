@@ -2,44 +2,65 @@ package helper
 
 import (
 	"errors"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwt_secret = []byte(os.Getenv("JWT_SECRET"))
-
 type JWTCLAIMS struct {
 	UserID uint   `json:"user_id"`
 	Email  string `json:"email"`
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(email string, userId uint) (string, error) {
+// JWT menandatangani dan memverifikasi access token, secret dan umur token berasal dari config
+type JWT struct {
+	secret     []byte
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+func NewJWT(secret string, accessTTL, refreshTTL time.Duration) *JWT {
+	return &JWT{secret: []byte(secret), AccessTTL: accessTTL, RefreshTTL: refreshTTL}
+}
+
+// GenerateJWT membuat access token berumur pendek dengan jti unik
+func (j *JWT) GenerateJWT(email string, userId uint) (string, *JWTCLAIMS, error) {
+	jti, err := GenerateRandomToken(16)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
 	claims := JWTCLAIMS{
 		UserID: userId,
 		Email:  email,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  &jwt.NumericDate{Time: time.Now()},
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(now.Add(j.AccessTTL)),
+			IssuedAt:  &jwt.NumericDate{Time: now},
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwt_secret)
+	signed, err := token.SignedString(j.secret)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return signed, &claims, nil
 }
 
-func ValidateJWT(tokenstring string) (*JWTCLAIMS, error) {
+func (j *JWT) ValidateJWT(tokenstring string) (*JWTCLAIMS, error) {
 	token, err := jwt.ParseWithClaims(tokenstring, &JWTCLAIMS{}, func(t *jwt.Token) (interface{}, error) {
-		return jwt_secret, nil
+		return j.secret, nil
 	})
 	if err != nil {
 		return nil, err
 	}
 
 	claims, ok := token.Claims.(*JWTCLAIMS)
-	if !ok || !token.Valid {
+	if !ok || !token.Valid || claims.ID == "" {
 		return nil, errors.New("invalid token")
 	}
 
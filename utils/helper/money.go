@@ -0,0 +1,73 @@
+package helper
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// harga selalu disimpan sebagai integer dalam satuan terkecil (minor unit),
+// nilai di map ini adalah jumlah digit desimal tiap mata uang (ISO 4217)
+var currencyExponent = map[string]int{
+	"IDR": 2,
+	"USD": 2,
+	"EUR": 2,
+	"SGD": 2,
+	"MYR": 2,
+	"JPY": 0,
+}
+
+const DefaultCurrency = "IDR"
+
+func IsValidCurrency(currency string) bool {
+	_, ok := currencyExponent[currency]
+	return ok
+}
+
+// MulMoney mengalikan harga satuan dengan jumlah item tanpa overflow diam-diam
+func MulMoney(price int64, qty int) (int64, error) {
+	if price < 0 || qty < 0 {
+		return 0, ErrInvalidPrice
+	}
+	if qty != 0 && price > math.MaxInt64/int64(qty) {
+		return 0, ErrMoneyOverflow
+	}
+
+	return price * int64(qty), nil
+}
+
+func AddMoney(a, b int64) (int64, error) {
+	if b > 0 && a > math.MaxInt64-b {
+		return 0, ErrMoneyOverflow
+	}
+
+	return a + b, nil
+}
+
+// FormatMoney menampilkan minor unit sebagai string, contoh 1500050 IDR -> "IDR 15000.50"
+func FormatMoney(amount int64, currency string) string {
+	exp, ok := currencyExponent[currency]
+	if !ok {
+		exp = 2
+	}
+
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	if exp == 0 {
+		return fmt.Sprintf("%s %s%d", currency, sign, amount)
+	}
+
+	unit := int64(1)
+	for i := 0; i < exp; i++ {
+		unit *= 10
+	}
+
+	fraction := fmt.Sprintf("%d", amount%unit)
+	fraction = strings.Repeat("0", exp-len(fraction)) + fraction
+
+	return fmt.Sprintf("%s %s%d.%s", currency, sign, amount/unit, fraction)
+}
@@ -0,0 +1,47 @@
+package helper
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// GenerateRandomToken membuat token acak (hex) untuk refresh token dan jti
+func GenerateRandomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// HashToken supaya token asli tidak pernah disimpan di redis
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// SignToken menambahkan tanda tangan HMAC ke payload, hasil: "<payload>.<sig>"
+func (j *JWT) SignToken(payload string) string {
+	mac := hmac.New(sha256.New, j.secret)
+	mac.Write([]byte(payload))
+	return payload + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignedToken mengembalikan payload jika tanda tangan cocok
+func (j *JWT) VerifySignedToken(token string) (string, bool) {
+	i := strings.LastIndex(token, ".")
+	if i <= 0 {
+		return "", false
+	}
+
+	payload := token[:i]
+	if !hmac.Equal([]byte(j.SignToken(payload)), []byte(token)) {
+		return "", false
+	}
+
+	return payload, true
+}
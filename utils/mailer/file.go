@@ -0,0 +1,48 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// FileMailer menulis setiap email sebagai file .eml, untuk development lokal
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if dir == "" {
+		dir = "mails"
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("mailer: %v", err)
+	}
+
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s_%s.eml", time.Now().Format("20060102T150405.000000000"), unsafeFileChars.ReplaceAllString(msg.To, "_"))
+	f, err := os.Create(filepath.Join(m.dir, name))
+	if err != nil {
+		return fmt.Errorf("mailer: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := buildMessage(m.from, msg).WriteTo(f); err != nil {
+		return fmt.Errorf("mailer: %v", err)
+	}
+
+	return nil
+}
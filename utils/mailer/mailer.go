@@ -0,0 +1,71 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gopkg.in/gomail.v2"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Mailer adalah backend pengirim email yang dipakai worker
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type Config struct {
+	// smtp, file atau memory
+	Driver string
+	From   string
+
+	//smtp
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// starttls (default) atau ssl
+	TLS string
+
+	//file
+	Dir string
+}
+
+func New(cfg Config) (Mailer, error) {
+	switch strings.ToLower(cfg.Driver) {
+	case "", "smtp":
+		return NewSMTPMailer(cfg)
+	case "file":
+		return NewFileMailer(cfg.Dir, cfg.From)
+	case "memory":
+		return NewMemoryMailer(), nil
+	}
+
+	return nil, fmt.Errorf("mailer: driver %q tidak dikenal", cfg.Driver)
+}
+
+// buildMessage menyusun email multipart, text jadi alternatif dari html
+func buildMessage(from string, msg Message) *gomail.Message {
+	m := gomail.NewMessage()
+	m.SetHeader("From", from)
+	m.SetHeader("To", msg.To)
+	m.SetHeader("Subject", msg.Subject)
+
+	switch {
+	case msg.Text != "" && msg.HTML != "":
+		m.SetBody("text/plain", msg.Text)
+		m.AddAlternative("text/html", msg.HTML)
+	case msg.HTML != "":
+		m.SetBody("text/html", msg.HTML)
+	default:
+		m.SetBody("text/plain", msg.Text)
+	}
+
+	return m
+}
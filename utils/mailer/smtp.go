@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gopkg.in/gomail.v2"
+)
+
+type SMTPMailer struct {
+	from   string
+	dialer *gomail.Dialer
+}
+
+func NewSMTPMailer(cfg Config) (*SMTPMailer, error) {
+	if cfg.Host == "" {
+		cfg.Host = "smtp.gmail.com"
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+
+	dialer := gomail.NewDialer(cfg.Host, cfg.Port, cfg.Username, cfg.Password)
+	switch strings.ToLower(cfg.TLS) {
+	case "", "starttls":
+		dialer.SSL = false
+	case "ssl":
+		dialer.SSL = true
+	default:
+		return nil, fmt.Errorf("mailer: mode tls %q tidak dikenal", cfg.TLS)
+	}
+
+	return &SMTPMailer{from: cfg.From, dialer: dialer}, nil
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := m.dialer.DialAndSend(buildMessage(m.from, msg)); err != nil {
+		return fmt.Errorf("send email: %w", err)
+	}
+
+	return nil
+}
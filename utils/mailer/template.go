@@ -0,0 +1,167 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+const DefaultLocale = "id"
+
+// Locales yang punya template, locale lain jatuh ke DefaultLocale
+var Locales = []string{"id", "en"}
+
+// Templates adalah nama template per job type
+var Templates = []string{"welcome", "verify", "reset", "receipt", "cart_reminder"}
+
+type Rendered struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Renderer menyimpan template html (dengan layout) dan plain text untuk setiap locale
+type Renderer struct {
+	html map[string]*htmltemplate.Template
+	text map[string]*texttemplate.Template
+}
+
+func NewRenderer() (*Renderer, error) {
+	r := &Renderer{
+		html: make(map[string]*htmltemplate.Template),
+		text: make(map[string]*texttemplate.Template),
+	}
+
+	for _, locale := range Locales {
+		for _, name := range Templates {
+			key := locale + "/" + name
+
+			html, err := htmltemplate.New(name).Option("missingkey=zero").
+				ParseFS(templateFS, "templates/layout.html", "templates/"+key+".html")
+			if err != nil {
+				return nil, fmt.Errorf("mailer: template %s: %v", key, err)
+			}
+
+			text, err := texttemplate.New(name).Option("missingkey=zero").
+				ParseFS(templateFS, "templates/"+key+".txt")
+			if err != nil {
+				return nil, fmt.Errorf("mailer: template %s: %v", key, err)
+			}
+
+			r.html[key] = html
+			r.text[key] = text
+		}
+	}
+
+	return r, nil
+}
+
+// NormalizeLocale mengubah "en-US" jadi "en", locale yang tidak dikenal jadi DefaultLocale
+func NormalizeLocale(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		locale = locale[:i]
+	}
+
+	for _, l := range Locales {
+		if l == locale {
+			return l
+		}
+	}
+
+	return DefaultLocale
+}
+
+// FormatDuration menulis masa berlaku link sesuai locale, misal "24 jam" atau "30 minutes"
+func FormatDuration(d time.Duration, locale string) string {
+	n, unit := int64(d/time.Minute), "minute"
+	if d >= time.Hour && d%time.Hour == 0 {
+		n, unit = int64(d/time.Hour), "hour"
+	}
+
+	if NormalizeLocale(locale) == "en" {
+		if n != 1 {
+			unit += "s"
+		}
+		return fmt.Sprintf("%d %s", n, unit)
+	}
+
+	if unit == "hour" {
+		return fmt.Sprintf("%d jam", n)
+	}
+	return fmt.Sprintf("%d menit", n)
+}
+
+func (r *Renderer) Render(name, locale string, data map[string]interface{}) (*Rendered, error) {
+	locale = NormalizeLocale(locale)
+	key := locale + "/" + name
+
+	text, ok := r.text[key]
+	if !ok {
+		return nil, fmt.Errorf("mailer: template %q tidak ada", name)
+	}
+
+	view := make(map[string]interface{}, len(data)+2)
+	for k, v := range data {
+		view[k] = v
+	}
+	view["Locale"] = locale
+
+	var subject, body, html bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", view); err != nil {
+		return nil, fmt.Errorf("mailer: %v", err)
+	}
+	if err := text.ExecuteTemplate(&body, "text", view); err != nil {
+		return nil, fmt.Errorf("mailer: %v", err)
+	}
+
+	view["Subject"] = strings.TrimSpace(subject.String())
+	if err := r.html[key].ExecuteTemplate(&html, "layout", view); err != nil {
+		return nil, fmt.Errorf("mailer: %v", err)
+	}
+
+	return &Rendered{
+		Subject: view["Subject"].(string),
+		HTML:    html.String(),
+		Text:    body.String(),
+	}, nil
+}
+
+// SampleData dipakai endpoint preview supaya designer bisa melihat template tanpa job asli
+func SampleData(name, locale string) map[string]interface{} {
+	data := map[string]interface{}{
+		"Name": "Budi",
+		"Link": "http://localhost:8080/verify-email?token=contoh",
+	}
+
+	if name == "verify" {
+		data["ExpiresIn"] = FormatDuration(24*time.Hour, locale)
+	}
+
+	if name == "reset" {
+		data["ExpiresIn"] = FormatDuration(30*time.Minute, locale)
+	}
+
+	if name == "cart_reminder" {
+		data["ItemCount"] = 2
+	}
+
+	if name == "receipt" {
+		data["OrderID"] = 42
+		data["TotalItem"] = 3
+		data["Total"] = "IDR 75000.00"
+		data["Lines"] = []map[string]interface{}{
+			{"Name": "Kopi susu", "Amount": 2, "SubTotal": "IDR 50000.00"},
+			{"Name": "Roti bakar", "Amount": 1, "SubTotal": "IDR 25000.00"},
+		}
+	}
+
+	return data
+}
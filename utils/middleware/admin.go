@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"api_shope/utils/helper"
+	"crypto/subtle"
+	"net/http"
+)
+
+// AdminMiddleware melindungi endpoint admin dengan header X-Admin-Token.
+// jika token kosong, semua endpoint admin ditolak.
+func AdminMiddleware(adminToken string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.Header.Get("X-Admin-Token")
+			if adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+				helper.WriteError(w, http.StatusForbidden, "bukan admin")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -11,23 +11,40 @@ type key int
 
 const UserContextKey key = 0
 
-func AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			helper.WriteError(w, http.StatusUnauthorized, "tak ada token")
-			return
-		}
-
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		claims, err := helper.ValidateJWT(tokenString)
-		if err != nil {
-			helper.WriteError(w, http.StatusForbidden, err.Error())
-			return
-		}
-
-		ctx := context.WithValue(r.Context(), UserContextKey, claims)
-		next.ServeHTTP(w, r.WithContext(ctx))
-
-	})
+// TokenChecker dipakai untuk mengecek apakah access token sudah dicabut
+type TokenChecker interface {
+	IsAccessTokenRevoked(claims *helper.JWTCLAIMS) (bool, error)
+}
+
+func AuthMiddleware(checker TokenChecker, jwt *helper.JWT) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				helper.WriteError(w, http.StatusUnauthorized, "tak ada token")
+				return
+			}
+
+			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+			claims, err := jwt.ValidateJWT(tokenString)
+			if err != nil {
+				helper.WriteError(w, http.StatusForbidden, err.Error())
+				return
+			}
+
+			revoked, err := checker.IsAccessTokenRevoked(claims)
+			if err != nil {
+				helper.WriteError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if revoked {
+				helper.WriteError(w, http.StatusUnauthorized, helper.ErrTokenRevoked.Error())
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserContextKey, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+
+		})
+	}
 }